@@ -0,0 +1,303 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/extractor"
+	"github.com/spf13/cobra"
+)
+
+var cacheJSON bool
+var pruneOlderThan int
+var pruneDryRun bool
+var addLanguage string
+var addPath string
+var addIndex string
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local Sample cache",
+	Long: `Inspects and maintains the local sample cache without contacting the
+	sample aggregator. See "oneapi-cli clean" to remove the cache altogether.`,
+}
+
+var cacheInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show what the cache holds",
+	Long:  `Shows the size of the cache, the age of each index, the number of cached sample tarballs and if the cache is locked`,
+	Run: func(cmd *cobra.Command, args []string) {
+		info, err := aggregator.CacheStats(cacheDir())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		info.System = aggregator.SystemCache(systemFilePath, baseFilePath)
+		if cacheJSON {
+			fmt.Printf("%s\n", prettyPrint(info))
+			return
+		}
+
+		fmt.Printf("Cache:    %s\n", info.Path)
+		fmt.Printf("Layout:   %d\n", info.Layout)
+		if info.System != "" {
+			fmt.Printf("System:   %s (read-only)\n", info.System)
+		}
+		fmt.Printf("Size:     %s\n", extractor.HumanSize(info.Size))
+		fmt.Printf("Tarballs: %d (%s), %d not referenced by any index\n", info.Tarballs, extractor.HumanSize(info.TarballSize), info.Unreferenced)
+		if info.Locked {
+			fmt.Printf("Locked:   yes, a sync failed. Run oneapi-cli clean\n")
+		} else {
+			fmt.Printf("Locked:   no\n")
+		}
+		if info.Importing {
+			fmt.Printf("Import:   in progress, remove import.lock from the cache if none is running\n")
+		}
+		for _, i := range info.Indexes {
+			fmt.Printf("Index %s: %d samples, %d local, updated %s ago, %d snapshot(s)\n", i.Language, i.Samples, i.Local, time.Since(i.Modified).Round(time.Minute), i.Snapshots)
+		}
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove unused Sample tarballs",
+	Long: `Removes the sample tarballs no index refers to anymore and, with
+	--older-than, those stored more than that many days ago. Versions used by
+	projects and lockfiles are kept.
+
+	i.e. oneapi-cli cache prune --older-than 90`,
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan := time.Duration(pruneOlderThan) * 24 * time.Hour
+		removed, err := aggregator.Prune(cacheDir(), olderThan, pruneDryRun)
+		if cacheJSON {
+			fmt.Printf("%s\n", prettyPrint(removed))
+		} else {
+			var freed int64
+			for _, c := range removed {
+				fmt.Printf("%s %s sample %s %s\n", pruneVerb(), c.Language, c.Path, c.SHA)
+				freed += c.Size
+			}
+			fmt.Printf("%d tarball(s), %s\n", len(removed), extractor.HumanSize(freed))
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+	},
+}
+
+func pruneVerb() string {
+	if pruneDryRun {
+		return "would remove"
+	}
+	return "removed"
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the cache for corruption",
+	Long: `Re-hashes every cached sample tarball against the digest recorded when
+	it was downloaded and checks it can be read. Indexes and their snapshots are
+	checked too. Tarballs cached before digests were recorded are reported as
+	unverified. Exits with 3 when anything is corrupt or changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := aggregator.VerifyCache(cacheDir())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		bad := 0
+		for _, r := range results {
+			if r.Status == aggregator.VerifyCorrupt || r.Status == aggregator.VerifyMismatch {
+				bad++
+			}
+		}
+		if cacheJSON {
+			fmt.Printf("%s\n", prettyPrint(results))
+		} else {
+			for _, r := range results {
+				if r.Detail != "" {
+					fmt.Printf("%-10s %s - %s\n", r.Status, r.File, r.Detail)
+				} else {
+					fmt.Printf("%-10s %s\n", r.Status, r.File)
+				}
+			}
+			fmt.Printf("%d file(s) checked, %d problem(s)\n", len(results), bad)
+		}
+		if bad > 0 {
+			os.Exit(3)
+		}
+	},
+}
+
+var cacheExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the cache to a single archive",
+	Long: `Writes the whole cache to a tar.gz, to be imported on another machine
+	with "oneapi-cli cache import"
+
+	i.e. oneapi-cli cache export /tmp/oneapi-cache.tar.gz`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the archive to write")
+			os.Exit(1)
+		}
+		f, err := os.Create(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if err := aggregator.ExportCache(cacheDir(), f); err != nil {
+			f.Close()
+			os.Remove(args[0])
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+	},
+}
+
+var cacheImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Merge an exported cache into the cache",
+	Long: `Merges an archive written by "oneapi-cli cache export" into the local
+	cache. Local files are kept, an imported index that differs from the local
+	one is kept as a snapshot so "oneapi-cli versions" knows its samples.
+
+	i.e. oneapi-cli cache import /tmp/oneapi-cache.tar.gz`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the archive to import")
+			os.Exit(1)
+		}
+		if err := os.MkdirAll(cacheDir(), 0750); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		res, err := aggregator.ImportCache(cacheDir(), args[0])
+		if err == aggregator.ErrCacheLock {
+			fmt.Println("The sample cache is locked, a sync failed. Run oneapi-cli clean")
+			os.Exit(2)
+		}
+		if err == aggregator.ErrCacheBusy {
+			fmt.Println("Another oneapi-cli is importing into the sample cache, see oneapi-cli cache info")
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		if cacheJSON {
+			fmt.Printf("%s\n", prettyPrint(res))
+			return
+		}
+		fmt.Printf("%d file(s) added, %d already present, %d index(es) kept as snapshots\n", res.Added, res.Skipped, res.Snapshots)
+	},
+}
+
+var cacheAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Sideload a sample tarball into the cache",
+	Long: `Adds a sample tarball that did not come from the aggregator, i.e. one
+	handed over by a colleague or built while developing a sample, to the
+	cache. It is listed with the samples of the language, marked as local,
+	and replaces a sample of the aggregator with the same path. The index
+	entry describing the sample is read from --index, an entry like those of
+	<language>.json.
+
+	i.e. oneapi-cli cache add --language cpp --path my/sample ./cpp.tar.gz --index entry.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the sample tarball to add")
+			os.Exit(1)
+		}
+		if addLanguage == "" {
+			fmt.Println("Please pass the language of the sample with --language")
+			os.Exit(1)
+		}
+
+		var s aggregator.Sample
+		if addIndex != "" {
+			b, err := ioutil.ReadFile(addIndex)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if err := json.Unmarshal(b, &s); err != nil {
+				fmt.Printf("invalid index entry %s - %v\n", addIndex, err)
+				os.Exit(1)
+			}
+		}
+		if addPath != "" {
+			s.Path = addPath
+		}
+		if s.Path == "" {
+			fmt.Println("Please pass the path of the sample with --path or in the index entry")
+			os.Exit(1)
+		}
+
+		s, tarPath, err := aggregator.AddLocalSample(cacheDir(), addLanguage, s, args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if cacheJSON {
+			fmt.Printf("%s\n", prettyPrint(struct {
+				aggregator.Sample
+				File string `json:"file"`
+			}{s, tarPath}))
+			return
+		}
+		fmt.Printf("Added %s sample %s (%s) as %s\n", addLanguage, s.Path, s.Fields.Name, s.SHA)
+		if !languageEnabled(addLanguage) {
+			fmt.Printf("warning: %s is not an enabled language, pass --languages to see the sample\n", addLanguage)
+		}
+	},
+}
+
+func languageEnabled(language string) bool {
+	for _, l := range enabledLanguages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheDir is the cache of the aggregator, upgraded to the current layout
+func cacheDir() string {
+	dir := aggregator.CacheDir(baseFilePath)
+	applied, err := aggregator.MigrateCache(dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	for _, a := range applied {
+		fmt.Fprintf(os.Stderr, "Upgraded the cache: %s\n", a)
+	}
+	return dir
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheInfoCmd, cachePruneCmd, cacheVerifyCmd, cacheExportCmd, cacheImportCmd, cacheAddCmd)
+	cacheCmd.PersistentFlags().BoolVarP(&cacheJSON, "json", "j", false, "output as JSON")
+	cachePruneCmd.Flags().IntVar(&pruneOlderThan, "older-than", 0, "also remove tarballs stored more than this many days ago")
+	cachePruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "show what would be removed without removing anything")
+	cacheAddCmd.Flags().StringVar(&addLanguage, "language", "", "language of the sample")
+	cacheAddCmd.Flags().StringVar(&addPath, "path", "", "path of the sample, overrides the path of the index entry")
+	cacheAddCmd.Flags().StringVar(&addIndex, "index", "", "JSON file with the index entry of the sample")
+}
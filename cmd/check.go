@@ -5,46 +5,182 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"runtime"
+	"strings"
 
+	"github.com/intel/oneapi-cli/pkg/aggregator"
 	"github.com/intel/oneapi-cli/pkg/deps"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes of check, tools calling it rely on them
+const (
+	checkOK           = 0 // every dependency is present
+	checkUsage        = 1 // invalid arguments or unknown sample
+	checkNoRoot       = 2 // the oneAPI root could not be found
+	checkMissing      = 3 // at least one dependency is missing
+	checkUnverifiable = 4 // nothing is missing, but some dependencies could not be checked
+	checkNoIndex      = 5 // the sample index could not be fetched or the cache is locked
+)
+
 var depsParam []string
-var root string
+var checkSample string
+var checkLang string
+var checkJSON bool
+var checkScript string
+
+// checkResult is the JSON output of check
+type checkResult struct {
+	*deps.Report
+	Sample string     `json:"sample,omitempty"`
+	OK     bool       `json:"ok"`
+	Error  string     `json:"error,omitempty"`
+	Plan   *deps.Plan `json:"plan,omitempty"`   // how to install what is missing
+	Script string     `json:"script,omitempty"` // the plan as a script for this OS
+}
 
 // checkCmd represents the check command
 var checkCmd = &cobra.Command{
-	Use:    "check",
-	Short:  "check dependencies",
-	Hidden: true,
-	Long:   `check dependencies, returns an error/retrieve-it- message if dependencies are absent`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:   "check",
+	Short: "Check the dependencies of a sample",
+	Long: `Checks dependencies are installed, given with --deps or as the
+	dependencies of a sample with --sample (path or name)
+
+	i.e. oneapi-cli check --deps mkl,compiler|icx --json
+	     oneapi-cli check -s cpp --sample my/sample
+
+	Dependencies are oneAPI components (i.e. mkl), pkg-config packages
+	(pkg|<name>|<url>), compilers (compiler|<name>), commands on the PATH
+	(cmd|<name>), environment variables (env|<name>), Python modules
+	(python|<name>), shared libraries (lib|<name>) and files, absolute or
+	relative to the oneAPI root (file|<path>). Other kinds may take a URL to
+	obtain them after the name. Packages are found with pkg-config or, when it
+	is not installed or does not know them, from the .pc files of
+	PKG_CONFIG_PATH, the oneAPI root and the system. --json writes a report with the kind, status,
+	path, version and remediation URL of each.
+
+	Names may restrict the version with >=, <=, >, <, ==, != or a pattern
+	after @, i.e. compiler|icx>=2023.1 or mkl@2024.*
 
-		if root == "" {
-			//Find the oneAPI root
-			var err error
-			root, err = deps.GetOneAPIRoot()
+	Compilers are looked up in a table built into the CLI, updated by the
+	sample aggregator. Compilers in compilers.json of the oneapi-cli user
+	config directory (i.e. ~/.config/oneapi-cli) are added to it or replace
+	those with the same name. Only they may tell how to probe the version of
+	a compiler, paths of every table are relative to the compiler directory.
+
+	When dependencies are missing a script installing them is shown: the
+	components with the installers of their toolkits, packages with apt-get,
+	dnf or zypper and Python modules with pip. --script <file> saves it.
+	With --json it is in "script" and the steps in "plan".
+
+	Exit codes:
+	  0  every dependency is present
+	  1  invalid arguments or unknown sample
+	  2  no oneAPI installation could be found
+	  3  at least one dependency is missing
+	  4  nothing is missing, but some dependencies could not be checked
+	  5  the sample index could not be fetched or the sample cache is locked`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dependencies := depsParam
+		if checkSample != "" {
+			a, err := loadAggregator()
+			if err == aggregator.ErrCacheLock {
+				checkFailed(checkNoIndex, fmt.Errorf("the sample cache is locked, run oneapi-cli clean"))
+			}
+			if err == aggregator.ErrCacheBusy {
+				checkFailed(checkNoIndex, fmt.Errorf("the sample cache is being imported into, retry when the import is done"))
+			}
 			if err != nil {
-				fmt.Println(err) //Failed to find the Env, may be unset.
-				os.Exit(-1)
+				checkFailed(checkNoIndex, fmt.Errorf("failed to fetch the sample index - %v", err))
+			}
+			s, ok := a.FindSample(checkLang, checkSample)
+			if !ok {
+				s, ok = a.FindSampleByName(checkLang, checkSample)
 			}
+			if !ok {
+				checkFailed(checkUsage, fmt.Errorf("there is no %s sample %s in the index", checkLang, checkSample))
+			}
+			dependencies = append(append([]string(nil), s.Fields.Dependencies...), depsParam...)
+		} else if len(depsParam) == 0 {
+			checkFailed(checkUsage, fmt.Errorf("please pass the dependencies to check with --deps or a sample with --sample"))
+		}
 
+		//Find the oneAPI root
+		root, err := getOneAPIRoot()
+		if err != nil {
+			checkFailed(checkNoRoot, err) //Failed to find the Env, may be unset.
 		}
 
 		//Check the deps at the found root.
-		msg, errCode := deps.CheckDeps(depsParam, root)
-		if errCode != 0 {
-			fmt.Println(msg)
-			os.Exit(errCode)
+		report := deps.Check(dependencies, root)
+		code := checkOK
+		for _, d := range report.Dependencies {
+			if d.Status == deps.StatusMissing {
+				code = checkMissing
+				break
+			}
+			if d.Status == deps.StatusUnverifiable {
+				code = checkUnverifiable
+			}
+		}
+
+		var plan *deps.Plan
+		var script string
+		if p := report.Plan(); !p.Empty() {
+			plan = p
+			script = p.Script(runtime.GOOS)
+		}
+		if checkScript != "" && script != "" {
+			if err := ioutil.WriteFile(checkScript, []byte(script), 0755); err != nil {
+				checkFailed(checkUsage, fmt.Errorf("failed to save the script - %v", err))
+			}
 		}
 
+		if checkJSON {
+			fmt.Printf("%s\n", prettyPrint(checkResult{Report: report, Sample: checkSample, OK: code == checkOK, Plan: plan, Script: script}))
+			os.Exit(code)
+		}
+		for _, d := range report.Dependencies {
+			line := fmt.Sprintf("%-12s %-9s %s", d.Status, d.Kind, d.Name)
+			if d.Version != "" {
+				line += " " + d.Version
+			}
+			if d.Detail != "" {
+				line += " - " + d.Detail
+			}
+			fmt.Println(line)
+		}
+		if msg := report.Message(); msg != "" {
+			fmt.Printf("\n%s\n", strings.TrimRight(msg, "\n"))
+		}
+		if script != "" {
+			if checkScript != "" {
+				fmt.Printf("\nA script installing what is missing was saved to %s\n", checkScript)
+			} else {
+				fmt.Printf("\nTo install what is missing, run this script (or save it with --script <file>):\n\n%s", script)
+			}
+		}
+		os.Exit(code)
 	},
 }
 
+// checkFailed reports an error that stopped the check, as JSON with --json
+func checkFailed(code int, err error) {
+	if checkJSON {
+		fmt.Printf("%s\n", prettyPrint(checkResult{Report: &deps.Report{Root: oneAPIRoot, Dependencies: []deps.Dependency{}}, Sample: checkSample, Error: err.Error()}))
+	} else {
+		fmt.Println(err)
+	}
+	os.Exit(code)
+}
+
 func init() {
 	rootCmd.AddCommand(checkCmd)
 	checkCmd.Flags().StringSliceVarP(&depsParam, "deps", "", nil, "comma seperated dependency array")
-	checkCmd.Flags().StringVar(&root, "oneapi-root", "", "(optional) path to oneAPI root, default attempts to use environment varible ONEAPI_ROOT")
+	checkCmd.Flags().StringVar(&checkSample, "sample", "", "check the dependencies of this sample, by path or name")
+	checkCmd.Flags().StringVarP(&checkLang, "sampleLangauge", "s", "cpp", "language of the sample")
+	checkCmd.Flags().BoolVarP(&checkJSON, "json", "j", false, "output as JSON")
+	checkCmd.Flags().StringVar(&checkScript, "script", "", "save the script installing missing dependencies to this file")
 }
@@ -6,7 +6,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/intel/oneapi-cli/pkg/aggregator"
 	"github.com/spf13/cobra"
@@ -18,7 +17,7 @@ var cleanCmd = &cobra.Command{
 	Short: "Clean Sample Cache",
 	Long:  `Removes local Sample Cache`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := os.RemoveAll(filepath.Join(baseFilePath, aggregator.AggregatorLocalAPILevel)); err != nil {
+		if err := os.RemoveAll(aggregator.CacheDir(baseFilePath)); err != nil {
 			fmt.Println("Failed to clean sample cache.")
 			fmt.Printf("%s \n", err)
 			os.Exit(1)
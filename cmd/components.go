@@ -0,0 +1,69 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/intel/oneapi-cli/pkg/deps"
+	"github.com/spf13/cobra"
+)
+
+var componentsJSON bool
+
+// componentsResult is the JSON output of components
+type componentsResult struct {
+	Root       string           `json:"root"`
+	Components []deps.Component `json:"components"`
+}
+
+// componentsCmd represents the components command
+var componentsCmd = &cobra.Command{
+	Use:   "components",
+	Short: "List the components installed in oneAPI",
+	Long: `Lists every component installed in the oneAPI root with all its
+	versions, the version "latest" points to and the toolkit it comes with.
+	The root is --oneapi-root, ONEAPI_ROOT or the first installation found.
+
+	i.e. oneapi-cli components --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		root, err := getOneAPIRoot()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		components, err := deps.Components(root)
+		if err != nil {
+			fmt.Printf("failed to list the components of %s - %v\n", root, err)
+			os.Exit(1)
+		}
+
+		if componentsJSON {
+			fmt.Printf("%s\n", prettyPrint(componentsResult{Root: root, Components: components}))
+			return
+		}
+		fmt.Printf("oneAPI root: %s\n\n", root)
+		for _, c := range components {
+			versions := strings.Join(c.Versions, ", ")
+			if versions == "" {
+				versions = "-"
+			}
+			line := fmt.Sprintf("%-22s %s", c.Name, versions)
+			if c.Latest != "" {
+				line += fmt.Sprintf(" (latest %s)", c.Latest)
+			}
+			if c.Suite != "" {
+				line += "  " + c.Suite
+			}
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(componentsCmd)
+	componentsCmd.Flags().BoolVarP(&componentsJSON, "json", "j", false, "output as JSON")
+}
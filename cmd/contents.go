@@ -0,0 +1,65 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/extractor"
+	"github.com/spf13/cobra"
+)
+
+var contentsLang string
+var contentsJSON bool
+
+// contentsCmd represents the contents command
+var contentsCmd = &cobra.Command{
+	Use:   "contents",
+	Short: "List the files of a Sample",
+	Long: `Lists the files a sample contains, their sizes and the total footprint
+	without creating the sample, along with any template variables it declares
+
+	i.e. oneapi-cli contents -s cpp my/long/path/from/index/json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the sample to list")
+			os.Exit(1)
+		}
+
+		tarPath, _, vars, err := fetchSample(contentsLang, args[0], "")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		l, err := extractor.List(tarPath, selectionOptions())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+
+		if contentsJSON {
+			fmt.Printf("%s\n", prettyPrint(struct {
+				*extractor.Listing
+				Variables []aggregator.TemplateVariable `json:"variables,omitempty"`
+			}{l, vars}))
+			return
+		}
+		fmt.Print(l)
+		if len(vars) > 0 {
+			fmt.Println("\nTemplate variables, set with create --set key=value:")
+			for _, v := range vars {
+				fmt.Printf("  %s (default %s) %s\n", v.Name, v.Default, v.Description)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(contentsCmd)
+	contentsCmd.Flags().StringVarP(&contentsLang, "sampleLangauge", "s", "cpp", "specific language of the sample you want to list")
+	addSelectionFlags(contentsCmd)
+	contentsCmd.Flags().BoolVarP(&contentsJSON, "json", "j", false, "output as JSON")
+}
@@ -4,15 +4,26 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/intel/oneapi-cli/pkg/aggregator"
 	"github.com/intel/oneapi-cli/pkg/extractor"
+	"github.com/intel/oneapi-cli/pkg/project"
 	"github.com/spf13/cobra"
 )
 
 var sampleLang string
+var onConflict string
+var dryRun bool
+var includeGlobs []string
+var excludeGlobs []string
+var stripComponents int
+var gitInit bool
+var setValues []string
+var sampleSHA string
 
 // listCmd represents the list command
 var createCmd = &cobra.Command{
@@ -21,7 +32,31 @@ var createCmd = &cobra.Command{
 	Hidden: true,
 	Long: `Creates the sample based on the passed in path
 
-	i.e. oneapi-cli create -s cpp my/long/path/from/index/json /tmp/mynewproject`,
+	i.e. oneapi-cli create -s cpp my/long/path/from/index/json /tmp/mynewproject
+
+	When files already exist in the destination --on-conflict decides what happens:
+	overwrite (default), fail, skip, backup (existing file is renamed to .orig)
+	or ask (prompt for every file)
+
+	--include and --exclude select parts of the sample by glob, matched against
+	the names shown by "oneapi-cli contents". --strip-components drops leading
+	directories, i.e. to take one sub project out of its wrapping directory:
+
+	oneapi-cli create -s cpp --include 'wrapper/subA' --strip-components 2 my/sample /tmp/subA
+
+	Samples may declare template variables, i.e. the project name, shown by
+	"oneapi-cli contents". --set replaces them in file contents and names:
+
+	oneapi-cli create -s cpp --set PROJECT_NAME=fractal my/sample /tmp/fractal
+
+	The project records the sample it was created from in .oneapi-sample.json,
+	--git also makes it a git repository with the pristine sample as first commit
+
+	--sha creates an older version of the sample, as listed by "oneapi-cli versions".
+	Only versions still in the local cache can be created.
+
+	--dry-run shows the files that would be created, and which already exist,
+	without writing anything`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		//Arg 0 being sample
@@ -32,21 +67,173 @@ var createCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		tarPath, err := aggregator.GetTarBall(baseFilePath, baseURL, sampleLang, args[0])
+		policy, err := extractor.ParseConflictPolicy(onConflict)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		values, err := project.ParseValues(setValues)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		tarPath, src, vars, err := fetchSample(sampleLang, args[0], sampleSHA)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(2)
 		}
-		err = extractor.ExtractTarGz(tarPath, args[1])
+		opts := selectionOptions()
+		opts.OnConflict = policy
+		opts.Ask = askConflict
+		opts.Substitutions, err = project.Substitutions(vars, values)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if dryRun {
+			l, err := extractor.List(tarPath, opts)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(3)
+			}
+			checked := l.Against(args[1])
+			fmt.Print(checked)
+			if len(checked.Existing()) > 0 {
+				fmt.Printf("existing files would be handled with --on-conflict=%s\n", policy)
+			}
+			return
+		}
+
+		res, err := project.Create(tarPath, args[1], src, opts, cliVersion())
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(3)
 		}
+		pinVersions(sourcePin(src))
+		printExtractResult(res)
+
+		if gitInit {
+			initGit(args[1])
+		}
 
 	},
 }
 
+// fetchSample returns the cached tarball of a sample, downloading it if
+// needed, where it came from and the template variables it declares. An
+// empty sha is the version in the current index.
+func fetchSample(language string, path string, sha string) (string, project.Source, []aggregator.TemplateVariable, error) {
+	a := getAggregator()
+	src := project.Source{URL: a.GetURL(), Language: language, Path: path}
+
+	sample, ok := a.FindSample(language, path)
+	if sha != "" && (!ok || sample.SHA != sha) {
+		return fetchVersion(a, language, path, sha)
+	}
+	if !ok {
+		fmt.Printf("warning: %s is not in the %s sample index, its SHA will not be recorded\n", path, language)
+		tarPath, err := a.TarBall(language, path)
+		return tarPath, src, nil, err
+	}
+
+	src.Name = sample.Fields.Name
+	src.SHA = sample.SHA
+	src.IndexVersion, _ = a.IndexVersion(language)
+	tarPath, err := a.SampleTarBall(language, sample)
+	return tarPath, src, sample.Fields.TemplateVariables(), err
+}
+
+// fetchVersion returns the cached tarball of an older version of a sample
+func fetchVersion(a *aggregator.Aggregator, language string, path string, sha string) (string, project.Source, []aggregator.TemplateVariable, error) {
+	src := project.Source{URL: a.GetURL(), Language: language, Path: path, SHA: sha}
+	versions, err := a.Versions(language, path)
+	if err != nil {
+		return "", src, nil, err
+	}
+	for _, v := range versions {
+		if v.SHA != sha {
+			continue
+		}
+		tarPath, ok := a.CachedTarBall(language, path, sha)
+		if !ok {
+			return "", src, nil, fmt.Errorf("version %s of %s was last seen %s but is no longer cached, the aggregator only serves the current version", sha, path, v.Seen.Format("2006-01-02"))
+		}
+		src.Name = v.Sample.Fields.Name
+		return tarPath, src, v.Sample.Fields.TemplateVariables(), nil
+	}
+	return "", src, nil, fmt.Errorf("no version %s of %s %s sample is known, see oneapi-cli versions", sha, language, path)
+}
+
+// initGit makes the project a git repository, a failure does not undo the project
+func initGit(root string) {
+	created, err := project.InitGit(root)
+	if err != nil {
+		fmt.Printf("failed to initialise a git repository - %v\n", err)
+		os.Exit(4)
+	}
+	if !created {
+		fmt.Printf("%s is already inside a git repository, not initialising another\n", root)
+	}
+}
+
+// addSelectionFlags adds the flags selecting which parts of a sample are used
+func addSelectionFlags(c *cobra.Command) {
+	c.Flags().StringSliceVar(&includeGlobs, "include", nil, "only use files matching these globs, '**' matches any number of directories")
+	c.Flags().StringSliceVar(&excludeGlobs, "exclude", nil, "skip files matching these globs")
+	c.Flags().IntVar(&stripComponents, "strip-components", 0, "remove this many leading directories from file names")
+}
+
+// selectionOptions returns extractor options for the selection flags
+func selectionOptions() extractor.Options {
+	return extractor.Options{Include: includeGlobs, Exclude: excludeGlobs, StripComponents: stripComponents}
+}
+
+// printExtractResult reports files that were not simply written
+func printExtractResult(res *extractor.Result) {
+	for _, f := range res.Skipped {
+		fmt.Printf("skipped existing %s\n", f)
+	}
+	for _, f := range res.BackedUp {
+		fmt.Printf("backed up existing %s to %s%s\n", f, f, extractor.BackupSuffix)
+	}
+}
+
+var conflictAnswers = map[string]extractor.ConflictPolicy{
+	"o": extractor.ConflictOverwrite,
+	"s": extractor.ConflictSkip,
+	"b": extractor.ConflictBackup,
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// askConflict prompts on the terminal for what to do with an existing file
+func askConflict(name string) (extractor.ConflictPolicy, error) {
+	for {
+		fmt.Printf("%s already exists. [o]verwrite, [s]kip, [b]ackup or [a]bort? ", name)
+		answer, err := stdin.ReadString('\n')
+		if err != nil {
+			return extractor.ConflictFail, fmt.Errorf("no answer given for %s - %v", name, err)
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "a" {
+			return extractor.ConflictFail, fmt.Errorf("aborted at %s", name)
+		}
+		if p, ok := conflictAnswers[answer]; ok {
+			return p, nil
+		}
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(createCmd)
 	createCmd.Flags().StringVarP(&sampleLang, "sampleLangauge", "s", "cpp", "specific language of the samples you want to create")
+	addSelectionFlags(createCmd)
+	createCmd.Flags().StringVar(&sampleSHA, "sha", "", "create this version of the sample instead of the current one")
+	createCmd.Flags().StringArrayVar(&setValues, "set", nil, "set a template variable of the sample, key=value (repeatable)")
+	createCmd.Flags().BoolVar(&gitInit, "git", false, "initialise a git repository with the sample as first commit")
+	createCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be created without writing anything")
+	createCmd.Flags().StringVar(&onConflict, "on-conflict", extractor.ConflictOverwrite.String(), fmt.Sprintf("what to do with files that already exist (%s)", strings.Join(extractor.ConflictPolicyNames(), ", ")))
 }
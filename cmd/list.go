@@ -65,6 +65,10 @@ var listCmd = &cobra.Command{
 		}
 
 		for _, s := range getAggregator().Samples[language] {
+			if s.Local {
+				fmt.Printf("%s (local):\n\t%s\n", s.Fields.Name, s.Fields.Description)
+				continue
+			}
 			fmt.Printf("%s:\n\t%s\n", s.Fields.Name, s.Fields.Description)
 		}
 	},
@@ -0,0 +1,64 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intel/oneapi-cli/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var lockOutput string
+
+// lockCmd represents the lock command
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Pin the Samples of a workspace",
+	Long: `Writes a lockfile pinning every project of a workspace file to the exact
+	SHA, source URL and tarball digest of its sample. Projects already created
+	are pinned to the version they were created from. The lockfile is written
+	next to the workspace file (workspace.yaml is locked in workspace.lock),
+	see "oneapi-cli restore" to recreate the workspace from it.
+
+	i.e. oneapi-cli lock workspace.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the workspace file to lock")
+			os.Exit(1)
+		}
+
+		w, err := workspace.Load(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		l, err := w.Lock(fetchProject)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		out := lockOutput
+		if out == "" {
+			out = workspace.LockPath(args[0])
+		}
+		if err := l.Write(out); err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		pinVersions(lockPins(l)...)
+		for _, p := range l.Projects {
+			fmt.Printf("locked %s sample %s in %s at %s\n", p.Language, p.Sample, p.Destination, p.SHA)
+		}
+		fmt.Printf("wrote %s\n", out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	lockCmd.Flags().StringVarP(&lockOutput, "output", "o", "", "where to write the lockfile")
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
+	"github.com/intel/oneapi-cli/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var restoreMirror string
+var restoreJSON bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Recreate the Samples of a lockfile",
+	Long: `Recreates every project of a lockfile written by "oneapi-cli lock" from
+	exactly the sample tarballs it pins. Tarballs are taken from the local cache,
+	then from --mirror, then from the aggregator if it still serves the locked
+	SHA. Every tarball must match its locked digest. Nothing newer is ever used
+	in place of a locked sample, restore fails for that project instead. So does
+	a project that already exists but was created from another version.
+
+	A mirror is laid out like the local cache, <language>/<path>/<sha>/<language>.tar.gz,
+	and is a URL or a directory, i.e. a copy of ~/.oneapi-cli/v1 from another machine.
+
+	i.e. oneapi-cli restore --mirror https://mirror.example.com/samples workspace.lock`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the lockfile to restore")
+			os.Exit(1)
+		}
+
+		l, err := workspace.ReadLock(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		//Keep the locked versions from retention before the index is synced
+		pinVersions(lockPins(l)...)
+
+		//Use the aggregator the samples were locked from, unless told otherwise
+		if !cmd.Flags().Changed("url") {
+			if url, ok := lockedURL(l); ok {
+				baseURL = url
+			}
+		}
+		getAggregator()
+
+		results := l.Workspace().Apply(func(p workspace.Project) (string, project.Source, []aggregator.TemplateVariable, error) {
+			lp, ok := l.Find(p)
+			if !ok {
+				return "", project.Source{}, nil, fmt.Errorf("%s is not in the lockfile", p.Destination)
+			}
+			tarPath, err := lockedTarBall(lp)
+			return tarPath, lp.Source(), lp.Variables, err
+		}, workspace.Options{Jobs: workspaceJobs, CLIVersion: cliVersion(), Exact: true})
+		pinApplied(results)
+
+		if printApplyResults(results, restoreJSON) > 0 {
+			os.Exit(3)
+		}
+	},
+}
+
+// lockPins are the versions of the samples a lockfile uses
+func lockPins(l *workspace.Lockfile) []aggregator.Pin {
+	var pins []aggregator.Pin
+	for _, p := range l.Projects {
+		pins = append(pins, aggregator.Pin{Language: p.Language, Path: p.Sample, SHA: p.SHA})
+	}
+	return pins
+}
+
+// lockedURL is the aggregator URL when all of the projects were locked from the same one
+func lockedURL(l *workspace.Lockfile) (string, bool) {
+	url := ""
+	for _, p := range l.Projects {
+		if url != "" && p.URL != url {
+			return "", false
+		}
+		url = p.URL
+	}
+	return url, url != ""
+}
+
+// lockedTarBall finds the exact tarball a project was locked to
+func lockedTarBall(lp workspace.LockedProject) (string, error) {
+	a := getAggregator()
+	base := a.GetLocalPath()
+
+	if tarPath, ok := a.CachedTarBall(lp.Language, lp.Sample, lp.SHA); ok {
+		return tarPath, lp.Verify(tarPath)
+	}
+
+	var mirrorErr error
+	if restoreMirror != "" {
+		tarPath, err := aggregator.MirrorTarBall(base, restoreMirror, lp.Language, lp.Sample, lp.SHA)
+		if err == nil {
+			if err := lp.Verify(tarPath); err != nil {
+				os.Remove(tarPath)
+				return "", err
+			}
+			return tarPath, nil
+		}
+		mirrorErr = err
+	}
+
+	sample, ok := a.FindSample(lp.Language, lp.Sample)
+	switch {
+	case !ok:
+		err := fmt.Errorf("locked to %s, which is not in the cache and %s no longer serves the sample", lp.SHA, a.GetURL())
+		return "", withMirrorErr(err, mirrorErr)
+	case sample.SHA != lp.SHA:
+		err := fmt.Errorf("locked to %s, which is not in the cache and %s now serves %s", lp.SHA, a.GetURL(), sample.SHA)
+		return "", withMirrorErr(err, mirrorErr)
+	}
+
+	tarPath, err := a.SampleTarBall(lp.Language, sample)
+	if err != nil {
+		return "", withMirrorErr(err, mirrorErr)
+	}
+	return tarPath, lp.Verify(tarPath)
+}
+
+func withMirrorErr(err error, mirrorErr error) error {
+	if mirrorErr == nil {
+		return err
+	}
+	return fmt.Errorf("%v (%v)", err, mirrorErr)
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringVar(&restoreMirror, "mirror", "", "URL or directory laid out like the local cache to fetch locked samples from")
+	restoreCmd.Flags().BoolVarP(&restoreJSON, "json", "j", false, "output as JSON")
+	restoreCmd.Flags().IntVar(&workspaceJobs, "jobs", workspace.DefaultJobs, "number of projects to create at once")
+}
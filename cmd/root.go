@@ -9,8 +9,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/deps"
+	"github.com/intel/oneapi-cli/pkg/project"
 	"github.com/intel/oneapi-cli/pkg/ui"
 	"github.com/spf13/cobra"
 )
@@ -21,14 +25,21 @@ const SampleLatestKey = "latest"
 
 const LocalStorageDefault = ".oneapi-cli"
 
+// SystemStorageEnv names the system cache, overriding the one next to the binary
+const SystemStorageEnv = "ONEAPI_CLI_SYSTEM_CACHE"
+
 var baseURL string
 var baseFilePath string
+var systemFilePath string
 var cAggregator *aggregator.Aggregator
 var defaultLanguages = []string{"cpp", "python", "fortran"}
 var enabledLanguages []string
 var userHome string
 var ignoreOS bool
 var bulk bool
+var keepVersions int
+var keepIndexes int
+var oneAPIRoot string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -43,32 +54,70 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			log.Fatal(err)
 		}
+		app.SetVersion(cliVersion())
+		if oneAPIRoot != "" {
+			app.SetOneAPIRoot(oneAPIRoot)
+		}
 		app.Show()
 
 	},
 }
 
 func getAggregator() *aggregator.Aggregator {
+	a, err := loadAggregator()
+	if err == aggregator.ErrCacheBusy {
+		fmt.Printf("The sample cache is being imported into, retry when the import is done.\n")
+		fmt.Printf("If no import is running remove %s\n", filepath.Join(aggregator.CacheDir(baseFilePath), "import.lock"))
+		os.Exit(1)
+	}
+	if err != nil && err != aggregator.ErrCacheLock {
+		//Most errors we are going to find are network related :/
+		fmt.Printf("Failed to fetch sample index, this *may* be your network/proxy environment.\nYou might try setting http_proxy in your environment, for example:\n")
+		fmt.Printf("\tLinux/Mac: export http_proxy=http://your.proxy:8080\n")
+		fmt.Printf("\tWindows: set http_proxy=http://your.proxy:8080\n")
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+	if err == aggregator.ErrCacheLock {
+		fmt.Printf("Local Sample cache is corrupt! Please clean the cache and retry!\n")
+		fmt.Printf("\toneapi-cli clean\n")
+		fmt.Printf("\toneapi-cli\n")
+		os.Exit(1)
+	}
+	return a
+}
+
+// loadAggregator is getAggregator for commands reporting its errors their own way
+func loadAggregator() (*aggregator.Aggregator, error) {
 	if cAggregator == nil {
-		var err error
-		cAggregator, err = aggregator.NewAggregator(baseURL, baseFilePath, enabledLanguages, ignoreOS, bulk)
-		if err != nil && err != aggregator.ErrCacheLock {
-			//Most errors we are going to find are network related :/
-			fmt.Printf("Failed to fetch sample index, this *may* be your network/proxy environment.\nYou might try setting http_proxy in your environment, for example:\n")
-			fmt.Printf("\tLinux/Mac: export http_proxy=http://your.proxy:8080\n")
-			fmt.Printf("\tWindows: set http_proxy=http://your.proxy:8080\n")
-			fmt.Printf("%v\n", err)
-			os.Exit(1)
+		a, err := aggregator.NewLayeredAggregator(baseURL, baseFilePath, systemFilePath, enabledLanguages, ignoreOS, bulk)
+		if err != nil {
+			return nil, err
 		}
-		if err == aggregator.ErrCacheLock {
-			fmt.Printf("Local Sample cache is corrupt! Please clean the cache and retry!\n")
-			fmt.Printf("\toneapi-cli clean\n")
-			fmt.Printf("\toneapi-cli\n")
-			os.Exit(1)
+		cAggregator = a
+		//Old versions are kept for "create --sha", within bounds. Only a
+		//new index makes versions old.
+		if cAggregator.Updated {
+			retention := aggregator.Retention{Versions: keepVersions, Snapshots: keepIndexes}
+			if _, err := cAggregator.ApplyRetention(retention); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to remove old versions from the cache - %v\n", err)
+			}
 		}
 	}
-	return cAggregator
+	return cAggregator, nil
+}
+
+// pinVersions keeps the versions of samples used by projects and lockfiles
+// in the cache, see aggregator.Pin
+func pinVersions(pins ...aggregator.Pin) {
+	if err := aggregator.AddPins(cacheDir(), pins...); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record the sample versions in use - %v\n", err)
+	}
+}
 
+// sourcePin is the version of a sample a project was created from
+func sourcePin(src project.Source) aggregator.Pin {
+	return aggregator.Pin{Language: src.Language, Path: src.Path, SHA: src.SHA}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -81,6 +130,7 @@ func Execute() {
 }
 
 func init() {
+	cobra.OnInitialize(useDataFiles)
 
 	var err error
 	userHome, err = os.UserHomeDir()
@@ -88,40 +138,142 @@ func init() {
 		fmt.Printf("Unable to locate Home Directory - %v\n", err)
 		os.Exit(1)
 	}
-	defaultBaseFilePath := filepath.Join(userHome, LocalStorageDefault)
+	defaultBaseFilePath := getUserCachePath(userHome)
 
 	rootCmd.PersistentFlags().StringVarP(&baseURL, "url", "u", getVersionInfo(), "URL of remote sample aggregator")
 	rootCmd.PersistentFlags().StringVarP(&baseFilePath, "directory", "d", defaultBaseFilePath, "location to store local oneapi samples cache")
+	rootCmd.PersistentFlags().StringVar(&systemFilePath, "system-directory", getSystemCachePath(), "location of a read-only samples cache shared by all users, read before the local cache")
 	rootCmd.PersistentFlags().StringSliceVarP(&enabledLanguages, "languages", "l", defaultLanguages, "enabled languages")
 	rootCmd.PersistentFlags().BoolVar(&ignoreOS, "ignore-os", false, "ignore Host-OS based filtering when showing/outputting samples")
 	rootCmd.PersistentFlags().BoolVar(&bulk, "full-sync", false, "download all samples at startup")
+	rootCmd.PersistentFlags().IntVar(&keepVersions, "keep-versions", aggregator.DefaultRetention.Versions, "versions of each sample kept in the cache when the index changes, 0 keeps all. Versions projects and lockfiles use are always kept")
+	rootCmd.PersistentFlags().StringVar(&oneAPIRoot, "oneapi-root", "", "path to the oneAPI installation to check dependencies against, default uses ONEAPI_ROOT or looks for one")
+	rootCmd.PersistentFlags().IntVar(&keepIndexes, "keep-indexes", aggregator.DefaultRetention.Snapshots, "versions of each sample index kept in the cache, 0 keeps all")
 
 }
 
-// looks at the command bin path and looks for "samples-version-tag.txt" which
+// useDataFiles has dependency checks read the data files the aggregator
+// served into the system and user caches. Compiler tables are read in the
+// order of the system cache, the user cache and the user, each over the one
+// before, and the served ones are checked as they are fetched.
+func useDataFiles() {
+	var caches []string
+	if systemFilePath != "" {
+		caches = append(caches, aggregator.CacheDir(systemFilePath))
+	}
+	caches = append(caches, aggregator.CacheDir(baseFilePath))
+	dataFiles := func(name string) []string {
+		var paths []string
+		for _, c := range caches {
+			paths = append(paths, aggregator.DataFilePath(c, name))
+		}
+		return paths
+	}
 
-// points to which sample version to look at. If it cant find it it
-// returns the Latestkey const
-func getVersionInfo() string {
+	aggregator.ValidateDataFile(aggregator.CompilersDataFile, deps.ValidateCompilerTable)
+	tables := deps.CompilerTables{Served: dataFiles(aggregator.CompilersDataFile)}
+	if dir, err := os.UserConfigDir(); err == nil {
+		tables.User = append(tables.User, filepath.Join(dir, "oneapi-cli", aggregator.CompilersDataFile))
+	}
+	deps.UseCompilerTables(tables)
+	deps.UseMappings(deps.MappingSources{
+		Components:      dataFiles(aggregator.ComponentsDataFile),
+		SuiteComponents: dataFiles(aggregator.SuiteComponentsDataFile),
+		Suites:          dataFiles(aggregator.SuitesDataFile),
+		DistroPackages:  dataFiles(aggregator.DistroPackagesDataFile),
+	})
+}
+
+// getOneAPIRoot is the oneAPI installation of --oneapi-root, otherwise of
+// ONEAPI_ROOT or the first one found on the machine
+func getOneAPIRoot() (string, error) {
+	if oneAPIRoot == "" {
+		return deps.FindOneAPIRoot()
+	}
+	if info, err := os.Stat(oneAPIRoot); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("--oneapi-root %s is not a directory", oneAPIRoot)
+	}
+	return oneAPIRoot, nil
+}
+
+// getUserCachePath is ~/.oneapi-cli, or on Linux the XDG cache directory
+// unless ~/.oneapi-cli is already there from an earlier version
+func getUserCachePath(home string) string {
+	legacy := filepath.Join(home, LocalStorageDefault)
+	if runtime.GOOS != "linux" || aggregator.FileExists(legacy) {
+		return legacy
+	}
+	dir, err := os.UserCacheDir() // $XDG_CACHE_HOME or ~/.cache
+	if err != nil {
+		return legacy
+	}
+	return filepath.Join(dir, "oneapi-cli")
+}
+
+// getSystemCachePath finds the read-only cache shared by all users. An
+// administrator points to it with ONEAPI_CLI_SYSTEM_CACHE or, like
+// "samples-version-tag.txt", with "etc/samples-cache-path.txt" of the
+// install. Otherwise "share/oneapi-cli" of the install is used if present.
+func getSystemCachePath() string {
+	if dir := os.Getenv(SystemStorageEnv); dir != "" {
+		return dir
+	}
+	root, err := installRoot()
+	if err != nil {
+		return ""
+	}
+	if dir, err := readFirstLine(filepath.Join(root, "etc", "samples-cache-path.txt")); err == nil && dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		return dir
+	}
+	if dir := filepath.Join(root, "share", "oneapi-cli"); aggregator.FileExists(dir) {
+		return dir
+	}
+	return ""
+}
+
+// installRoot is the directory the binary was installed in, the parent of bin
+func installRoot() (string, error) {
 	bin, err := os.Executable()
 	if err != nil {
-		return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, SampleLatestKey)
+		return "", err
 	}
 	bin, err = filepath.EvalSymlinks(bin)
 	if err != nil {
-		return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, SampleLatestKey)
+		return "", err
 	}
-	versionPath := filepath.Join(filepath.Dir(filepath.Dir(bin)), "etc", "samples-version-tag.txt")
-	file, err := os.Open(versionPath)
+	return filepath.Dir(filepath.Dir(bin)), nil
+}
+
+func readFirstLine(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, SampleLatestKey)
+		return "", err
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	scanner.Scan()
 	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
+
+// looks at the command bin path and looks for "samples-version-tag.txt" which
+
+// points to which sample version to look at. If it cant find it it
+// returns the Latestkey const
+func getVersionInfo() string {
+	root, err := installRoot()
+	if err != nil {
+		return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, SampleLatestKey)
+	}
+	tag, err := readFirstLine(filepath.Join(root, "etc", "samples-version-tag.txt"))
+	if err != nil {
 		return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, SampleLatestKey)
 	}
-	return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, scanner.Text())
+	return fmt.Sprintf("%s/%s/", SamplesEndpointDefault, tag)
 }
@@ -0,0 +1,96 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intel/oneapi-cli/pkg/project"
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show local modifications to a created Sample",
+	Long: `Shows which files of a project created by oneapi-cli were added, modified
+	or deleted since it was created, and if a newer version of the sample is
+	available. Run it inside the project or pass the project directory.
+
+	i.e. oneapi-cli status /tmp/mynewproject`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		root, err := project.FindRoot(dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		m, err := project.ReadManifest(root)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		pinVersions(sourcePin(m.Sample))
+		changes, err := project.Diff(root, m.Files)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+
+		a := getAggregator()
+		status := project.Status{Root: root, Sample: m.Sample, Changes: *changes}
+		latest, ok := a.FindSample(m.Sample.Language, m.Sample.Path)
+		if !ok && m.Sample.Name != "" {
+			latest, ok = a.FindSampleByName(m.Sample.Language, m.Sample.Name)
+		}
+		if ok {
+			status.LatestSHA = latest.SHA
+			status.UpdateAvailable = latest.SHA != m.Sample.SHA
+		}
+
+		if statusJSON {
+			fmt.Printf("%s\n", prettyPrint(status))
+			return
+		}
+		printStatus(&status, ok)
+	},
+}
+
+func printStatus(status *project.Status, found bool) {
+	fmt.Printf("Project %s\n", status.Root)
+	fmt.Printf("Created from %s sample %s at %s\n\n", status.Sample.Language, status.Sample.Path, status.Sample.SHA)
+
+	if status.Clean() {
+		fmt.Printf("No local modifications\n")
+	}
+	for _, f := range status.Modified {
+		fmt.Printf("\tmodified: %s\n", f)
+	}
+	for _, f := range status.Added {
+		fmt.Printf("\tadded:    %s\n", f)
+	}
+	for _, f := range status.Deleted {
+		fmt.Printf("\tdeleted:  %s\n", f)
+	}
+
+	switch {
+	case !found:
+		fmt.Printf("\nThe sample is no longer in the sample index, unable to check for updates\n")
+	case status.UpdateAvailable:
+		fmt.Printf("\nA newer version of the sample is available (%s)\n", status.LatestSHA)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().BoolVarP(&statusJSON, "json", "j", false, "output as JSON")
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intel/oneapi-cli/pkg/project"
+	"github.com/spf13/cobra"
+)
+
+var upgradeDryRun bool
+var upgradeJSON bool
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade a created Sample to its latest version",
+	Long: `Applies the changes made to a sample since the project was created, keeping
+	local modifications. Files changed on both sides are merged line by line and
+	conflict markers are written where the changes overlap. Run it inside the
+	project or pass the project directory.
+
+	The version the project was created from must still be in the local sample
+	cache.
+
+	i.e. oneapi-cli upgrade /tmp/mynewproject`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		root, err := project.FindRoot(dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		m, err := project.ReadManifest(root)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		pinVersions(sourcePin(m.Sample))
+		a := getAggregator()
+		latest, ok := a.FindSample(m.Sample.Language, m.Sample.Path)
+		if !ok && m.Sample.Name != "" {
+			latest, ok = a.FindSampleByName(m.Sample.Language, m.Sample.Name)
+		}
+		if !ok {
+			fmt.Printf("%s is no longer in the %s sample index, unable to upgrade\n", m.Sample.Path, m.Sample.Language)
+			os.Exit(2)
+		}
+		if latest.SHA == m.Sample.SHA {
+			fmt.Printf("Already up to date at %s\n", m.Sample.SHA)
+			return
+		}
+
+		oldTar, ok := a.CachedTarBall(m.Sample.Language, m.Sample.Path, m.Sample.SHA)
+		if !ok {
+			fmt.Printf("The version the project was created from (%s) is not in the sample cache, unable to upgrade\n", m.Sample.SHA)
+			os.Exit(2)
+		}
+		newTar, err := a.SampleTarBall(m.Sample.Language, latest)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		to := m.Sample
+		to.Name = latest.Fields.Name
+		to.URL = a.GetURL()
+		to.Path = latest.Path
+		to.SHA = latest.SHA
+		to.IndexVersion, _ = a.IndexVersion(m.Sample.Language)
+
+		report, err := project.Upgrade(root, m, oldTar, newTar, to, upgradeDryRun)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+
+		if !upgradeDryRun {
+			pinVersions(sourcePin(to))
+		}
+
+		if upgradeJSON {
+			fmt.Printf("%s\n", prettyPrint(report))
+		} else {
+			printUpgrade(report)
+		}
+		if report.Conflicts() > 0 {
+			os.Exit(4)
+		}
+	},
+}
+
+func printUpgrade(report *project.UpgradeReport) {
+	verb := "Upgraded"
+	if upgradeDryRun {
+		verb = "Would upgrade"
+	}
+	fmt.Printf("%s from %s to %s\n", verb, report.From, report.To)
+	for _, f := range report.Files {
+		if f.Detail != "" {
+			fmt.Printf("\t%-9s %s (%s)\n", f.Action+":", f.Name, f.Detail)
+			continue
+		}
+		fmt.Printf("\t%-9s %s\n", f.Action+":", f.Name)
+	}
+	if n := report.Conflicts(); n > 0 {
+		fmt.Printf("\n%d file(s) need attention\n", n)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "show what would change without writing anything")
+	upgradeCmd.Flags().BoolVarP(&upgradeJSON, "json", "j", false, "output as JSON")
+}
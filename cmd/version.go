@@ -18,13 +18,18 @@ var versionCmd = &cobra.Command{
 	Short: "Show the CLI version information",
 	Long:  `Show the CLI version information`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if version == "" {
-			version = "devel"
-		}
-		fmt.Printf("%s\n", version)
+		fmt.Printf("%s\n", cliVersion())
 	},
 }
 
+// cliVersion returns the version this CLI was built as, "devel" when unset
+func cliVersion() string {
+	if version == "" {
+		return "devel"
+	}
+	return version
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,117 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
+	"github.com/spf13/cobra"
+)
+
+var versionsLang string
+var versionsJSON bool
+var versionsCompare []string
+
+// versionsCmd represents the versions command
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List the known versions of a Sample",
+	Long: `Lists every version of a sample seen in the sample index or kept in the
+	local cache, newest first. Cached versions can be created with
+	"oneapi-cli create --sha", --compare lists the files that changed between
+	two cached versions.
+
+	i.e. oneapi-cli versions -s cpp my/long/path/from/index/json
+	     oneapi-cli versions -s cpp --compare <old sha>,<new sha> my/long/path/from/index/json
+
+	How many versions are kept is set with --keep-versions and --keep-indexes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the sample to list the versions of")
+			os.Exit(1)
+		}
+		if len(versionsCompare) != 0 && len(versionsCompare) != 2 {
+			fmt.Println("Please pass the two versions to compare, i.e. --compare <old sha>,<new sha>")
+			os.Exit(1)
+		}
+
+		a := getAggregator()
+		if len(versionsCompare) == 2 {
+			compareVersions(a, versionsLang, args[0], versionsCompare[0], versionsCompare[1])
+			return
+		}
+
+		versions, err := a.Versions(versionsLang, args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if len(versions) == 0 {
+			fmt.Printf("no versions of %s sample %s are known\n", versionsLang, args[0])
+			os.Exit(2)
+		}
+
+		if versionsJSON {
+			fmt.Printf("%s\n", prettyPrint(versions))
+			return
+		}
+		for _, v := range versions {
+			var notes []string
+			if v.Current {
+				notes = append(notes, "current")
+			}
+			if v.Cached {
+				notes = append(notes, "cached")
+			}
+			fmt.Printf("%s  %s  %s\n", v.SHA, v.Seen.Format("2006-01-02 15:04"), strings.Join(notes, ", "))
+		}
+	},
+}
+
+func compareVersions(a *aggregator.Aggregator, language string, path string, from string, to string) {
+	var tars []string
+	for _, sha := range []string{from, to} {
+		tarPath, ok := a.CachedTarBall(language, path, sha)
+		if !ok {
+			fmt.Printf("version %s of %s is not in the cache\n", sha, path)
+			os.Exit(2)
+		}
+		tars = append(tars, tarPath)
+	}
+
+	c, err := project.Compare(tars[0], tars[1], selectionOptions())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(3)
+	}
+	if versionsJSON {
+		fmt.Printf("%s\n", prettyPrint(c))
+		return
+	}
+	if c.Clean() {
+		fmt.Printf("%s and %s have the same files\n", from, to)
+		return
+	}
+	for _, f := range c.Added {
+		fmt.Printf("added:    %s\n", f)
+	}
+	for _, f := range c.Modified {
+		fmt.Printf("modified: %s\n", f)
+	}
+	for _, f := range c.Deleted {
+		fmt.Printf("deleted:  %s\n", f)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(versionsCmd)
+	versionsCmd.Flags().StringVarP(&versionsLang, "sampleLangauge", "s", "cpp", "specific language of the sample")
+	versionsCmd.Flags().StringSliceVar(&versionsCompare, "compare", nil, "list the files changed between two cached versions, <old sha>,<new sha>")
+	addSelectionFlags(versionsCmd)
+	versionsCmd.Flags().BoolVarP(&versionsJSON, "json", "j", false, "output as JSON")
+}
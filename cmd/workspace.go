@@ -0,0 +1,157 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
+	"github.com/intel/oneapi-cli/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var workspaceJSON bool
+var workspaceJobs int
+
+// workspaceCmd represents the workspace command
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Create many Samples from a workspace file",
+	Long: `Works with workspace files, which list samples to create together. A
+	workspace file is YAML (or JSON when named .json):
+
+	projects:
+	  - language: cpp
+	    sample: my/long/path/from/index/json   # or the sample name
+	    destination: samples/first              # relative to the workspace file
+	    sha: 0123abcd                           # optional, pins the version
+	    set:                                    # optional template variables
+	      PROJECT_NAME: first`,
+}
+
+// workspaceApplyCmd represents the workspace apply command
+var workspaceApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create every Sample of a workspace file",
+	Long: `Creates every project listed in a workspace file, several at a time.
+	Projects that were already created from the same version of their sample
+	are left alone so apply can be run again at any time. Projects created from
+	an older version are reported as outdated, see "oneapi-cli upgrade".
+	Existing files are never replaced.
+
+	i.e. oneapi-cli workspace apply workspace.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			fmt.Println("Please pass the workspace file to apply")
+			os.Exit(1)
+		}
+
+		w, err := workspace.Load(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		getAggregator() //before any of the projects are worked on
+		results := w.Apply(fetchProject, workspace.Options{Jobs: workspaceJobs, CLIVersion: cliVersion(), Resolve: samplePath})
+		pinApplied(results)
+		if printApplyResults(results, workspaceJSON) > 0 {
+			os.Exit(3)
+		}
+	},
+}
+
+// printApplyResults prints the outcome of every project and a summary,
+// returning the number of failures
+func printApplyResults(results []workspace.Result, asJSON bool) int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	if asJSON {
+		fmt.Printf("%s\n", prettyPrint(results))
+		return counts[workspace.StatusFailed]
+	}
+	for _, r := range results {
+		fmt.Printf("%-10s %s sample %s in %s", r.Status, r.Language, r.Sample, r.Destination)
+		if r.SHA != "" {
+			fmt.Printf(" at %s", r.SHA)
+		}
+		if r.Error != "" {
+			fmt.Printf(" - %s", r.Error)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("\n%d created, %d up to date, %d outdated, %d failed\n",
+		counts[workspace.StatusCreated], counts[workspace.StatusUpToDate], counts[workspace.StatusOutdated], counts[workspace.StatusFailed])
+	return counts[workspace.StatusFailed]
+}
+
+// pinApplied keeps the versions of the samples the projects of a workspace
+// were created from in the cache
+func pinApplied(results []workspace.Result) {
+	var pins []aggregator.Pin
+	for _, r := range results {
+		if r.Status == workspace.StatusFailed {
+			continue
+		}
+		if m, err := project.ReadManifest(r.Destination); err == nil {
+			pins = append(pins, sourcePin(m.Sample))
+		}
+	}
+	pinVersions(pins...)
+}
+
+// samplePath is the path of a sample named by path or name, or the name
+// itself when there is no such sample
+func samplePath(language string, sample string) string {
+	a := getAggregator()
+	s, ok := a.FindSample(language, sample)
+	if !ok {
+		s, ok = a.FindSampleByName(language, sample)
+	}
+	if !ok {
+		return sample
+	}
+	return s.Path
+}
+
+// fetchProject looks up the sample of a workspace project by path or name.
+// A pinned SHA the index no longer serves can only come from the cache.
+func fetchProject(p workspace.Project) (string, project.Source, []aggregator.TemplateVariable, error) {
+	a := getAggregator()
+	sample, ok := a.FindSample(p.Language, p.Sample)
+	if !ok {
+		sample, ok = a.FindSampleByName(p.Language, p.Sample)
+	}
+	if !ok {
+		return "", project.Source{}, nil, fmt.Errorf("there is no %s sample %s in the index", p.Language, p.Sample)
+	}
+
+	src := project.Source{Name: sample.Fields.Name, URL: a.GetURL(), Language: p.Language, Path: sample.Path, SHA: sample.SHA}
+	src.IndexVersion, _ = a.IndexVersion(p.Language)
+	vars := sample.Fields.TemplateVariables()
+
+	if p.SHA != "" && p.SHA != sample.SHA {
+		tarPath, ok := a.CachedTarBall(p.Language, sample.Path, p.SHA)
+		if !ok {
+			return "", src, nil, fmt.Errorf("pinned to %s but the index serves %s, and %s is not in the cache", p.SHA, sample.SHA, p.SHA)
+		}
+		src.SHA = p.SHA
+		return tarPath, src, vars, nil
+	}
+
+	tarPath, err := a.SampleTarBall(p.Language, sample)
+	return tarPath, src, vars, err
+}
+
+func init() {
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceApplyCmd)
+	workspaceApplyCmd.Flags().BoolVarP(&workspaceJSON, "json", "j", false, "output as JSON")
+	workspaceApplyCmd.Flags().IntVar(&workspaceJobs, "jobs", workspace.DefaultJobs, "number of projects to create at once")
+}
@@ -5,6 +5,7 @@ package aggregator
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,9 +14,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
 type sampleWorkItem struct {
@@ -28,6 +31,7 @@ type sampleWorkItem struct {
 type Aggregator struct {
 	baseURL     *url.URL
 	localPath   string
+	systemPath  string
 	languages   []string
 	jobs        chan sampleWorkItem
 	results     chan error
@@ -35,6 +39,7 @@ type Aggregator struct {
 	sampleCount sync.WaitGroup
 	Samples     Samples
 	Online      bool
+	Updated     bool // an index changed since the last sync
 	ignoreOS    bool
 	Bulk        bool
 }
@@ -53,11 +58,24 @@ const cacheLockName = "lock"
 //ErrCacheLock Is thrown when aggregator's local cache is locked.
 var ErrCacheLock = errors.New("aggregator cache is locked")
 
+//importLockName is held while an archive is imported into the cache, unlike
+//cacheLockName it does not mean the cache is corrupt
+const importLockName = "import.lock"
+
+//ErrCacheBusy Is thrown when an archive is being imported into the local cache.
+var ErrCacheBusy = errors.New("aggregator cache is being imported into")
+
 //HTTPTimeout timeout in seconds for HTTP operations
 const HTTPTimeout = 10
 
 //NewAggregator Gives you a Aggregator.
 func NewAggregator(URL string, FilePath string, languages []string, ignoreOS bool, bulk bool) (*Aggregator, error) {
+	return NewLayeredAggregator(URL, FilePath, "", languages, ignoreOS, bulk)
+}
+
+//NewLayeredAggregator Gives you a Aggregator reading from the read-only system cache
+//in SystemPath before its own cache in FilePath. See SystemCache.
+func NewLayeredAggregator(URL string, FilePath string, SystemPath string, languages []string, ignoreOS bool, bulk bool) (*Aggregator, error) {
 	var a Aggregator
 	if URL == "" {
 		return nil, fmt.Errorf("no sample url passed")
@@ -87,6 +105,15 @@ func NewAggregator(URL string, FilePath string, languages []string, ignoreOS boo
 	if a.isLocked() {
 		return nil, ErrCacheLock
 	}
+	if FileExists(filepath.Join(a.localPath, importLockName)) {
+		return nil, ErrCacheBusy
+	}
+
+	//Bring caches of older CLIs up to date before using them
+	if _, err := MigrateCache(a.localPath); err != nil {
+		return nil, err
+	}
+	a.systemPath = SystemCache(SystemPath, FilePath)
 
 	if languages == nil || len(languages) < 1 {
 		return nil, fmt.Errorf("No Languages are being selected")
@@ -131,7 +158,7 @@ func sampleWorker(a *Aggregator, jobs <-chan sampleWorkItem, results chan<- erro
 }
 
 func (a *Aggregator) workSample(w sampleWorkItem) error {
-	_, err := GetTarBall(a.localPath, a.baseURL.String(), w.language, w.s.Path)
+	_, err := a.SampleTarBall(w.language, w.s)
 	if err != nil {
 		return err
 	}
@@ -171,10 +198,20 @@ func (a *Aggregator) syncLanguagesIndex() error {
 				update = true
 
 			}
+			//Keep the index being replaced, as of when it was fetched
+			if update && a.Online {
+				if err := a.snapshotFile(language, localPath); err != nil {
+					return err
+				}
+				a.Updated = true
+			}
 		} else {
 			if !a.Online {
-				log.Printf("operating offline and local cache for %s samples does not exist\n\t%s\n", language, indexErr)
-				continue
+				if !a.copySystemIndex(language) {
+					log.Printf("operating offline and local cache for %s samples does not exist\n\t%s\n", language, indexErr)
+					continue
+				}
+				log.Printf("operating offline, using the system cache for %s samples\n", language)
 			}
 			update = true
 		}
@@ -185,6 +222,15 @@ func (a *Aggregator) syncLanguagesIndex() error {
 			}
 
 		}
+		if FileExists(localPath) {
+			index, err := ioutil.ReadFile(localPath)
+			if err != nil {
+				return err
+			}
+			if err := a.snapshotIndex(language, index, time.Now()); err != nil {
+				return err
+			}
+		}
 		//Ensure Directory for local path of language exists
 		if err := os.MkdirAll(filepath.Join(a.localPath, language), 0750); err != nil {
 			return err
@@ -195,6 +241,11 @@ func (a *Aggregator) syncLanguagesIndex() error {
 	if len(workingLanguages) < 1 {
 		return fmt.Errorf("no working sample languages configured %v", a.languages)
 	}
+	if a.Online {
+		if err := a.syncDataFiles(); err != nil {
+			return err
+		}
+	}
 	//Overwrite with known working languages.
 	a.languages = workingLanguages
 
@@ -246,6 +297,11 @@ func (a *Aggregator) Update() error {
 		if jsonErr != nil {
 			return (jsonErr)
 		}
+		local, err := readLocalIndex(a.localPath, language)
+		if err != nil {
+			return err
+		}
+		collected = mergeLocal(collected, local)
 
 		if !a.ignoreOS {
 			collected = filterOnOS(collected)
@@ -307,6 +363,35 @@ func (a *Aggregator) GetLanguages() []string {
 	return a.languages
 }
 
+//FindSample looks up a sample of a language by its path
+func (a *Aggregator) FindSample(language string, path string) (Sample, bool) {
+	for _, s := range a.Samples[language] {
+		if s.Path == path {
+			return s, true
+		}
+	}
+	return Sample{}, false
+}
+
+//FindSampleByName looks up a sample of a language by its name
+func (a *Aggregator) FindSampleByName(language string, name string) (Sample, bool) {
+	for _, s := range a.Samples[language] {
+		if s.Fields.Name == name {
+			return s, true
+		}
+	}
+	return Sample{}, false
+}
+
+//IndexVersion identifies the local index of a language, it is the sha512 of the index
+func (a *Aggregator) IndexVersion(language string) (string, error) {
+	h, err := localHash(filepath.Join(a.localPath, language+".json"))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h), nil
+}
+
 //GetTarBall Path of the tarball
 func GetTarBall(base string, baseURL string, language string, path string) (tar string, err error) {
 	tarPath := filepath.Join(base, language, path, language+".tar.gz")
@@ -321,5 +406,76 @@ func GetTarBall(base string, baseURL string, language string, path string) (tar
 		return "", fmt.Errorf("failed to download sample '%s' - %v", path, err)
 	}
 
-	return tarPath, nil
+	return tarPath, recordDigest(tarPath)
+}
+
+var shaReg = regexp.MustCompile("^[A-Za-z0-9._-]+$")
+
+//versionedTarBallPath is where the tarball of a sample at a SHA is kept, these are
+//never replaced so older versions stay available after the index moves on
+func versionedTarBallPath(base string, language string, path string, sha string) (string, bool) {
+	if !shaReg.MatchString(sha) || strings.Trim(sha, ".") == "" {
+		return "", false
+	}
+	return filepath.Join(base, language, path, sha, language+".tar.gz"), true
+}
+
+//GetSampleTarBall Path of the tarball for the SHA of the sample, downloading it if needed.
+//Samples without a usable SHA are handled as GetTarBall does. Sideloaded samples are
+//never downloaded, the aggregator does not serve them.
+func GetSampleTarBall(base string, baseURL string, language string, s Sample) (tar string, err error) {
+	tarPath, ok := versionedTarBallPath(base, language, s.Path, s.SHA)
+	if !ok && !s.Local {
+		return GetTarBall(base, baseURL, language, s.Path)
+	}
+	if ok && FileExists(tarPath) {
+		return tarPath, nil
+	}
+	if s.Local {
+		return "", fmt.Errorf("the tarball of the sideloaded sample '%s' is no longer in the cache, add it again with oneapi-cli cache add", s.Path)
+	}
+
+	url := baseURL + "/" + s.Path + "/" + language + ".tar.gz"
+	if err := downloadFileDirect(tarPath, url); err != nil {
+		os.Remove(tarPath)
+		return "", fmt.Errorf("failed to download sample '%s' - %v", s.Path, err)
+	}
+	return tarPath, recordDigest(tarPath)
+}
+
+//CachedTarBall Path of a previously fetched tarball for the SHA of a sample. The
+//aggregator only serves the latest version, so older ones can only come from here.
+func CachedTarBall(base string, language string, path string, sha string) (tar string, ok bool) {
+	tarPath, ok := versionedTarBallPath(base, language, path, sha)
+	if !ok || !FileExists(tarPath) {
+		return "", false
+	}
+	return tarPath, true
+}
+
+//MirrorTarBall fetches the tarball of a sample at a SHA from a mirror into the local
+//cache. The mirror is laid out like the cache, <language>/<path>/<sha>/<language>.tar.gz,
+//and is either a URL or a local directory, i.e. a copy of another machine's cache.
+func MirrorTarBall(base string, mirror string, language string, path string, sha string) (tar string, err error) {
+	tarPath, ok := versionedTarBallPath(base, language, path, sha)
+	if !ok {
+		return "", fmt.Errorf("'%s' is not a SHA that can be mirrored", sha)
+	}
+	rel := language + "/" + path + "/" + sha + "/" + language + ".tar.gz"
+
+	u, err := url.Parse(mirror)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		err = downloadFileDirect(tarPath, strings.TrimSuffix(mirror, "/")+"/"+rel)
+	} else {
+		dir := mirror
+		if err == nil && u.Scheme == "file" {
+			dir = u.Path
+		}
+		err = copyFile(filepath.Join(dir, filepath.FromSlash(rel)), tarPath)
+	}
+	if err != nil {
+		os.Remove(tarPath)
+		return "", fmt.Errorf("failed to fetch sample '%s' at %s from mirror %s - %v", path, sha, mirror, err)
+	}
+	return tarPath, recordDigest(tarPath)
 }
@@ -3,6 +3,9 @@
 package aggregator
 
 import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -218,3 +221,147 @@ func TestOSFiltering(t *testing.T) {
 	}
 
 }
+
+func TestFindSample(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+
+	a, err := NewAggregator(td.ts.URL, td.dir, td.testLanguages, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := a.FindSample("cpp", "testrepo/simple-test-test")
+	if !ok || s.SHA != "2c755297a2073d7f317440e8429d274b284a9051" {
+		t.Errorf("failed to find the test sample, found %v", s)
+	}
+	if _, ok := a.FindSample("cpp", "testrepo/nope"); ok {
+		t.Errorf("found a sample that does not exist")
+	}
+	if s, ok := a.FindSampleByName("cpp", "Simple Test Test"); !ok || s.Path != "testrepo/simple-test-test" {
+		t.Errorf("failed to find the test sample by name, found %v", s)
+	}
+
+	v, err := a.IndexVersion("cpp")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(v) != 128 {
+		t.Errorf("index version should be a hex sha512, got %s", v)
+	}
+	if _, err := a.IndexVersion("cobol"); err == nil {
+		t.Errorf("there is no cobol index")
+	}
+}
+
+func TestGetSampleTarBall(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+	td.ts.Close()
+
+	body := "version one"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	s := Sample{Path: "zoo/zebra", SHA: "aaa"}
+	first, err := GetSampleTarBall(td.dir, ts.URL, "cpp", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//The aggregator moves on, the old version must stay in the cache
+	body = "version two"
+	s.SHA = "bbb"
+	second, err := GetSampleTarBall(td.dir, ts.URL, "cpp", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("both versions were stored at %s", first)
+	}
+
+	old, ok := CachedTarBall(td.dir, "cpp", s.Path, "aaa")
+	if !ok || old != first {
+		t.Fatalf("superseded version is not in the cache")
+	}
+	if b, _ := ioutil.ReadFile(old); string(b) != "version one" {
+		t.Errorf("superseded version was replaced, found '%s'", b)
+	}
+	if _, ok := CachedTarBall(td.dir, "cpp", s.Path, "ccc"); ok {
+		t.Errorf("found a version that was never fetched")
+	}
+	if _, ok := CachedTarBall(td.dir, "cpp", s.Path, "../../etc"); ok {
+		t.Errorf("a SHA must not be able to point outside of the cache")
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	const index = `[{"path":"p","sha":"1","example":{"name":"mandelbrot",
+		"projectOptions":[{"name":"PROJECT","description":"Project name","default":"mandelbrot"},"ignored",{"description":"no name"}],
+		"MakeVariables":{"TARGET":"mandel_bin","PROJECT":"duplicate","OUT":{"match":"@OUT@","default":"out"}}}}]`
+
+	var samples []Sample
+	if err := json.Unmarshal([]byte(index), &samples); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := samples[0].Fields.TemplateVariables()
+	expected := []TemplateVariable{
+		{Name: "PROJECT", Description: "Project name", Match: "mandelbrot", Default: "mandelbrot"},
+		{Name: "OUT", Match: "@OUT@", Default: "out"},
+		{Name: "TARGET", Match: "mandel_bin", Default: "mandel_bin"},
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("expected %v, got %v", expected, vars)
+	}
+
+	if vars := (Fields{}).TemplateVariables(); len(vars) != 0 {
+		t.Errorf("a sample without options has no variables, got %v", vars)
+	}
+}
+
+func TestMirrorTarBall(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+
+	//A copy of another cache as the mirror, both as a directory and served over HTTP
+	mirror := filepath.Join(td.dir, "mirror")
+	src := filepath.Join(mirror, "cpp", "zoo", "aaa", "cpp.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(src), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(src, []byte("mirrored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.FileServer(http.Dir(mirror)))
+	defer ts.Close()
+
+	for _, m := range []string{mirror, ts.URL} {
+		cache := filepath.Join(td.dir, "cache")
+		tarPath, err := MirrorTarBall(cache, m, "cpp", "zoo", "aaa")
+		if err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+		if cached, ok := CachedTarBall(cache, "cpp", "zoo", "aaa"); !ok || cached != tarPath {
+			t.Errorf("%s: mirrored tarball should be in the cache", m)
+		}
+		digest, err := FileDigest(tarPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha512.Sum512([]byte("mirrored"))
+		if digest != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: unexpected content mirrored", m)
+		}
+
+		if _, err := MirrorTarBall(cache, m, "cpp", "zoo", "bbb"); err == nil {
+			t.Errorf("%s: a version the mirror does not have should fail", m)
+		}
+		if _, ok := CachedTarBall(cache, "cpp", "zoo", "bbb"); ok {
+			t.Errorf("%s: a failed mirror must not leave anything in the cache", m)
+		}
+		os.RemoveAll(cache)
+	}
+}
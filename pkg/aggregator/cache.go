@@ -0,0 +1,597 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DigestSuffix names the file next to a tarball holding the sha512 it had
+// when it was downloaded. The index SHA of a sample is the SHA of its
+// source, not of its tarball, so this is what the cache is verified against.
+const DigestSuffix = ".sha512"
+
+// Outcomes of verifying a file of the cache
+const (
+	VerifyOK         = "ok"
+	VerifyCorrupt    = "corrupt"    // can not be read
+	VerifyMismatch   = "mismatch"   // content changed since it was stored
+	VerifyUnverified = "unverified" // readable, but there is nothing to check it against
+)
+
+// CacheDir is the cache directory NewAggregator uses under a base directory
+func CacheDir(filePath string) string {
+	return filepath.Join(filePath, AggregatorLocalAPILevel)
+}
+
+// CachedSample is a sample tarball in the cache
+type CachedSample struct {
+	Language string    `json:"language"`
+	Path     string    `json:"path"`
+	SHA      string    `json:"sha,omitempty"` // empty for tarballs cached before they were kept per SHA
+	File     string    `json:"file"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Referenced is true while the current index or a snapshot lists this version
+	Referenced bool `json:"referenced"`
+}
+
+// IndexInfo describes the index of a language in the cache
+type IndexInfo struct {
+	Language  string    `json:"language"`
+	Modified  time.Time `json:"modified"`
+	Samples   int       `json:"samples"`
+	Local     int       `json:"local"` // sideloaded samples, see AddLocalSample
+	Snapshots int       `json:"snapshots"`
+}
+
+// CacheInfo summarises the contents of a cache
+type CacheInfo struct {
+	Path         string      `json:"path"`
+	Layout       int         `json:"layout"`
+	System       string      `json:"system,omitempty"` // the read-only system cache, see SystemCache
+	Size         int64       `json:"size"`
+	Locked       bool        `json:"locked"`
+	Importing    bool        `json:"importing,omitempty"` // an archive is being imported, see ImportCache
+	Indexes      []IndexInfo `json:"indexes"`
+	Tarballs     int         `json:"tarballs"`
+	TarballSize  int64       `json:"tarballSize"`
+	Unreferenced int         `json:"unreferenced"`
+}
+
+// VerifyResult is the outcome of verifying a single file of the cache
+type VerifyResult struct {
+	File   string `json:"file"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ImportResult counts what an import did
+type ImportResult struct {
+	Added   int `json:"added"`
+	Skipped int `json:"skipped"` // already present
+	// Indexes differing from the local index are kept as snapshots
+	Snapshots int `json:"snapshots"`
+}
+
+// recordDigest stores the digest of a freshly downloaded tarball
+func recordDigest(tarPath string) error {
+	digest, err := FileDigest(tarPath)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(tarPath+DigestSuffix, []byte(digest+"\n"), 0644)
+}
+
+// cacheIndex is what the indexes of a language in the cache refer to
+type cacheIndex struct {
+	current map[string]bool            // paths in the current index
+	known   map[string]map[string]bool // path -> SHAs in any index
+	samples int
+	local   int // sideloaded samples
+	snaps   int
+}
+
+func (ci cacheIndex) refers(path string, sha string) bool {
+	if sha == "" {
+		return ci.current[path]
+	}
+	return ci.known[path][sha]
+}
+
+func readCacheIndex(base string, language string) cacheIndex {
+	ci := cacheIndex{current: make(map[string]bool), known: make(map[string]map[string]bool)}
+	add := func(samples []Sample) {
+		for _, s := range samples {
+			if ci.known[s.Path] == nil {
+				ci.known[s.Path] = make(map[string]bool)
+			}
+			ci.known[s.Path][s.SHA] = true
+		}
+	}
+
+	var current []Sample
+	if b, err := ioutil.ReadFile(filepath.Join(base, language+".json")); err == nil && json.Unmarshal(b, &current) == nil {
+		for _, s := range current {
+			ci.current[s.Path] = true
+		}
+		ci.samples = len(current)
+		add(current)
+	}
+	if local, err := readLocalIndex(base, language); err == nil {
+		for _, s := range local {
+			ci.current[s.Path] = true
+		}
+		ci.local = len(local)
+		add(local)
+	}
+	snaps, _ := snapshots(base, language)
+	ci.snaps = len(snaps)
+	for _, snap := range snaps {
+		if samples, err := snap.Samples(); err == nil {
+			add(samples)
+		}
+	}
+	return ci
+}
+
+// cacheLanguages lists the languages with an index or samples in the cache
+func cacheLanguages(base string) ([]string, error) {
+	infos, err := ioutil.ReadDir(base)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var languages []string
+	for _, info := range infos {
+		name := info.Name()
+		switch {
+		case info.IsDir() && name != historyDirName && name != dataDirName && !strings.HasPrefix(name, "."):
+		case !info.IsDir() && strings.HasSuffix(name, LocalIndexSuffix):
+			name = strings.TrimSuffix(name, LocalIndexSuffix)
+		case !info.IsDir() && filepath.Ext(name) == ".json":
+			name = strings.TrimSuffix(name, ".json")
+		default:
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			languages = append(languages, name)
+		}
+	}
+	sort.Strings(languages)
+	return languages, nil
+}
+
+// ListCachedSamples lists every sample tarball in the cache
+func ListCachedSamples(base string) ([]CachedSample, error) {
+	languages, err := cacheLanguages(base)
+	if err != nil {
+		return nil, err
+	}
+	var cached []CachedSample
+	for _, language := range languages {
+		ci := readCacheIndex(base, language)
+		root := filepath.Join(base, language)
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if info.IsDir() || info.Name() != language+".tar.gz" {
+				return nil
+			}
+			rel, err := filepath.Rel(root, filepath.Dir(p))
+			if err != nil {
+				return err
+			}
+			c := CachedSample{Language: language, File: p, Size: info.Size(), Modified: info.ModTime()}
+			c.Path, c.SHA = classifyTarBall(ci, filepath.ToSlash(rel))
+			c.Referenced = ci.refers(c.Path, c.SHA)
+			cached = append(cached, c)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cached, nil
+}
+
+// classifyTarBall tells <path>/<lang>.tar.gz from <path>/<sha>/<lang>.tar.gz,
+// dir being the directory holding the tarball
+func classifyTarBall(ci cacheIndex, dir string) (string, string) {
+	parent, sha := path.Dir(dir), path.Base(dir)
+	switch {
+	case ci.known[dir] != nil:
+		return dir, ""
+	case ci.known[parent] != nil:
+		return parent, sha
+	case parent != "." && shaReg.MatchString(sha):
+		return parent, sha
+	}
+	return dir, ""
+}
+
+// CacheStats summarises the cache at base
+func CacheStats(base string) (*CacheInfo, error) {
+	meta, err := ReadCacheMeta(base)
+	if err != nil {
+		return nil, err
+	}
+	info := CacheInfo{Path: base, Layout: meta.Layout, Locked: FileExists(filepath.Join(base, cacheLockName)), Importing: FileExists(filepath.Join(base, importLockName)), Indexes: []IndexInfo{}}
+	if !FileExists(base) {
+		return &info, nil // nothing fetched yet
+	}
+	err = filepath.Walk(base, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			info.Size += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	languages, err := cacheLanguages(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, language := range languages {
+		fi, err := os.Stat(filepath.Join(base, language+".json"))
+		if err != nil {
+			continue
+		}
+		ci := readCacheIndex(base, language)
+		info.Indexes = append(info.Indexes, IndexInfo{Language: language, Modified: fi.ModTime(), Samples: ci.samples, Local: ci.local, Snapshots: ci.snaps})
+	}
+
+	cached, err := ListCachedSamples(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cached {
+		info.Tarballs++
+		info.TarballSize += c.Size
+		if !c.Referenced {
+			info.Unreferenced++
+		}
+	}
+	return &info, nil
+}
+
+// Prune removes the tarballs no index refers to anymore and, when olderThan
+// is set, those stored longer ago than that. Pinned versions, see Pin, and
+// sideloaded samples, which can not be fetched again, are always kept.
+// Nothing is removed on a dry run.
+func Prune(base string, olderThan time.Duration, dryRun bool) ([]CachedSample, error) {
+	cached, err := ListCachedSamples(base)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := ReadPins(base)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[string]bool)
+	for _, p := range pins {
+		pinned[p.key()] = true
+	}
+	languages, err := cacheLanguages(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, language := range languages {
+		local, err := readLocalIndex(base, language)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range local {
+			pinned[Pin{Language: language, Path: s.Path, SHA: s.SHA}.key()] = true
+		}
+	}
+	var removed []CachedSample
+	for _, c := range cached {
+		if pinned[Pin{Language: c.Language, Path: c.Path, SHA: c.SHA}.key()] {
+			continue
+		}
+		if c.Referenced && (olderThan <= 0 || time.Since(c.Modified) < olderThan) {
+			continue
+		}
+		removed = append(removed, c)
+		if dryRun {
+			continue
+		}
+		if err := removeTarBall(base, c); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// removeTarBall removes a tarball, its digest and the directories left empty
+func removeTarBall(base string, c CachedSample) error {
+	if err := os.Remove(c.File); err != nil {
+		return err
+	}
+	os.Remove(c.File + DigestSuffix)
+	for dir := filepath.Dir(c.File); dir != base && strings.HasPrefix(dir, base); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // not empty
+		}
+	}
+	return nil
+}
+
+// VerifyCache re-hashes every tarball against the digest recorded when it
+// was downloaded and checks it can be read. Indexes are checked to parse
+// and snapshots to still match the hash they are named by.
+func VerifyCache(base string) ([]VerifyResult, error) {
+	var results []VerifyResult
+
+	languages, err := cacheLanguages(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, language := range languages {
+		index := filepath.Join(base, language+".json")
+		if FileExists(index) {
+			results = append(results, verifyIndex(index))
+		}
+		if local := localIndexPath(base, language); FileExists(local) {
+			results = append(results, verifyIndex(local))
+		}
+		snaps, err := snapshots(base, language)
+		if err != nil {
+			return nil, err
+		}
+		for _, snap := range snaps {
+			r := verifyIndex(snap.Path)
+			if h, err := localHash(snap.Path); r.Status == VerifyOK && (err != nil || hex.EncodeToString(h) != snap.Version) {
+				r = VerifyResult{File: snap.Path, Status: VerifyMismatch, Detail: "content does not match the snapshot name"}
+			}
+			results = append(results, r)
+		}
+	}
+
+	cached, err := ListCachedSamples(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cached {
+		r := verifyTarBall(c.File)
+		if r.Status == VerifyOK && !c.Referenced {
+			r.Detail = "not referenced by any index"
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func verifyIndex(p string) VerifyResult {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return VerifyResult{File: p, Status: VerifyCorrupt, Detail: err.Error()}
+	}
+	var samples []Sample
+	if err := json.Unmarshal(b, &samples); err != nil {
+		return VerifyResult{File: p, Status: VerifyCorrupt, Detail: err.Error()}
+	}
+	return VerifyResult{File: p, Status: VerifyOK}
+}
+
+func verifyTarBall(p string) VerifyResult {
+	if err := readTarGz(p); err != nil {
+		return VerifyResult{File: p, Status: VerifyCorrupt, Detail: err.Error()}
+	}
+	recorded, err := ioutil.ReadFile(p + DigestSuffix)
+	if os.IsNotExist(err) {
+		return VerifyResult{File: p, Status: VerifyUnverified, Detail: "no digest was recorded when it was downloaded"}
+	}
+	if err != nil {
+		return VerifyResult{File: p, Status: VerifyCorrupt, Detail: err.Error()}
+	}
+	digest, err := FileDigest(p)
+	if err != nil {
+		return VerifyResult{File: p, Status: VerifyCorrupt, Detail: err.Error()}
+	}
+	if digest != strings.TrimSpace(string(recorded)) {
+		return VerifyResult{File: p, Status: VerifyMismatch, Detail: "changed since it was downloaded"}
+	}
+	return VerifyResult{File: p, Status: VerifyOK}
+}
+
+// readTarGz reads a tar.gz to the end
+func readTarGz(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(ioutil.Discard, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// ExportCache writes the whole cache at base as a single tar.gz
+func ExportCache(base string, w io.Writer) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	err := filepath.Walk(base, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || rel == cacheLockName || rel == importLockName {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// ImportCache merges a cache exported by ExportCache into the cache at
+// base. Local files are never replaced, an imported index that differs
+// from the local one is kept as a snapshot so its versions stay known.
+// Aggregators are not created while importing, ErrCacheBusy is returned if
+// another import is running and ErrCacheLock if the cache is corrupt.
+func ImportCache(base string, archive string) (*ImportResult, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	if FileExists(filepath.Join(base, cacheLockName)) {
+		return nil, ErrCacheLock
+	}
+	//Other CLIs must not sync or read the cache half imported
+	lock, err := os.OpenFile(filepath.Join(base, importLockName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, ErrCacheBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock.Close()
+	defer os.Remove(lock.Name())
+
+	var res ImportResult
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return &res, nil
+		}
+		if err != nil {
+			return &res, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.ReplaceAll(hdr.Name, "\\", "/"))
+		if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return &res, fmt.Errorf("archive entry %s is outside of the cache", hdr.Name)
+		}
+		if name == cacheLockName || name == importLockName || name == cacheMetaName || name == pinsName {
+			continue // the state of the local cache
+		}
+
+		dest := filepath.Join(base, filepath.FromSlash(name))
+		if !FileExists(dest) {
+			if err := writeImported(dest, tr, hdr.ModTime); err != nil {
+				return &res, err
+			}
+			res.Added++
+			continue
+		}
+
+		//An index of a language with a local index of its own, sideloaded
+		//samples are not versioned
+		if !strings.Contains(name, "/") && path.Ext(name) == ".json" && !strings.HasSuffix(name, LocalIndexSuffix) {
+			b, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return &res, err
+			}
+			sum := sha512.Sum512(b)
+			local, err := localHash(dest)
+			if err != nil {
+				return &res, err
+			}
+			if !bytes.Equal(sum[:], local) {
+				snap := filepath.Join(historyDir(base, strings.TrimSuffix(name, ".json")), hex.EncodeToString(sum[:])+".json")
+				if !FileExists(snap) {
+					if err := writeImported(snap, bytes.NewReader(b), hdr.ModTime); err != nil {
+						return &res, err
+					}
+					res.Snapshots++
+					continue
+				}
+			}
+		}
+		res.Skipped++
+	}
+}
+
+func writeImported(dest string, r io.Reader, modified time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(dest) // never leave a partial file
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return err
+	}
+	return os.Chtimes(dest, modified, modified)
+}
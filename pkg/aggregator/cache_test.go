@@ -0,0 +1,272 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package aggregator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupCache builds a cache holding the current version (aaa) of zoo, an
+// old version (old) no index refers to and a tarball from before they
+// were kept per SHA
+func setupCache(t *testing.T) string {
+	t.Helper()
+	base, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(base, "cpp.json"), []byte(`[{"path":"zoo","sha":"aaa"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sha := range []string{"aaa", "old"} {
+		tarPath, _ := versionedTarBallPath(base, "cpp", "zoo", sha)
+		writeTestTarGz(t, tarPath, sha)
+		if err := recordDigest(tarPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestTarGz(t, filepath.Join(base, "cpp", "zoo", "cpp.tar.gz"), "legacy")
+	return base
+}
+
+func writeTestTarGz(t *testing.T, p string, content string) {
+	t.Helper()
+	var b bytes.Buffer
+	gzw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gzw)
+	tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(content))
+	tw.Close()
+	gzw.Close()
+	if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	base := setupCache(t)
+	defer os.RemoveAll(base)
+
+	cached, err := ListCachedSamples(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, c := range cached {
+		if c.Path != "zoo" {
+			t.Errorf("expected every tarball to belong to zoo, got %+v", c)
+		}
+		found[c.SHA] = c.Referenced
+	}
+	expected := map[string]bool{"aaa": true, "old": false, "": true}
+	if len(found) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, found)
+	}
+	for sha, ref := range expected {
+		if found[sha] != ref {
+			t.Errorf("version %q: expected referenced=%v", sha, ref)
+		}
+	}
+
+	ioutil.WriteFile(filepath.Join(base, cacheLockName), nil, 0644)
+	info, err := CacheStats(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Tarballs != 3 || info.Unreferenced != 1 || !info.Locked {
+		t.Errorf("unexpected summary %+v", info)
+	}
+	if len(info.Indexes) != 1 || info.Indexes[0].Language != "cpp" || info.Indexes[0].Samples != 1 {
+		t.Errorf("unexpected indexes %+v", info.Indexes)
+	}
+	if info.Size <= info.TarballSize {
+		t.Errorf("the total size should include the indexes and digests")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	base := setupCache(t)
+	defer os.RemoveAll(base)
+
+	//a pinned version is kept however old and unreferenced
+	if err := AddPins(base, Pin{Language: "cpp", Path: "zoo", SHA: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	aged := time.Now().Add(-48 * time.Hour)
+	if tarPath, ok := CachedTarBall(base, "cpp", "zoo", "old"); ok {
+		os.Chtimes(tarPath, aged, aged)
+	}
+	if removed, err := Prune(base, 24*time.Hour, false); err != nil || len(removed) != 0 {
+		t.Fatalf("expected the pinned version to be kept, removed %v %v", removed, err)
+	}
+	os.Remove(filepath.Join(base, pinsName))
+
+	removed, err := Prune(base, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].SHA != "old" {
+		t.Fatalf("expected only the unreferenced version to be pruned, got %v", removed)
+	}
+	if _, ok := CachedTarBall(base, "cpp", "zoo", "old"); !ok {
+		t.Fatalf("a dry run must not remove anything")
+	}
+
+	if _, err := Prune(base, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(filepath.Join(base, "cpp", "zoo", "old")) {
+		t.Errorf("pruned version directory should be removed")
+	}
+
+	//Age the legacy tarball
+	legacy := filepath.Join(base, "cpp", "zoo", "cpp.tar.gz")
+	os.Chtimes(legacy, aged, aged)
+	removed, err = Prune(base, 24*time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || FileExists(legacy) {
+		t.Errorf("expected the aged tarball to be pruned, got %v", removed)
+	}
+	if _, ok := CachedTarBall(base, "cpp", "zoo", "aaa"); !ok {
+		t.Errorf("a recent referenced version must be kept")
+	}
+}
+
+func TestVerifyCache(t *testing.T) {
+	base := setupCache(t)
+	defer os.RemoveAll(base)
+
+	a := &Aggregator{localPath: base}
+	if err := a.snapshotIndex("cpp", []byte(`[]`), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	status := func() map[string]string {
+		t.Helper()
+		results, err := VerifyCache(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := make(map[string]string)
+		for _, r := range results {
+			rel, _ := filepath.Rel(base, r.File)
+			s[filepath.ToSlash(rel)] = r.Status
+		}
+		return s
+	}
+
+	s := status()
+	for file, expected := range map[string]string{"cpp.json": VerifyOK, "cpp/zoo/aaa/cpp.tar.gz": VerifyOK, "cpp/zoo/old/cpp.tar.gz": VerifyOK, "cpp/zoo/cpp.tar.gz": VerifyUnverified} {
+		if s[file] != expected {
+			t.Errorf("%s: expected %s, got %s", file, expected, s[file])
+		}
+	}
+
+	//Tamper with everything
+	snaps, _ := snapshots(base, "cpp")
+	ioutil.WriteFile(snaps[0].Path, []byte(`[{"path":"x"}]`), 0644)
+	ioutil.WriteFile(filepath.Join(base, "cpp.json"), []byte(`not json`), 0644)
+	aaa, _ := CachedTarBall(base, "cpp", "zoo", "aaa")
+	writeTestTarGz(t, aaa, "changed")
+	old, _ := CachedTarBall(base, "cpp", "zoo", "old")
+	ioutil.WriteFile(old, []byte("garbage"), 0644)
+
+	s = status()
+	snapRel, _ := filepath.Rel(base, snaps[0].Path)
+	for file, expected := range map[string]string{"cpp.json": VerifyCorrupt, filepath.ToSlash(snapRel): VerifyMismatch, "cpp/zoo/aaa/cpp.tar.gz": VerifyMismatch, "cpp/zoo/old/cpp.tar.gz": VerifyCorrupt} {
+		if s[file] != expected {
+			t.Errorf("%s: expected %s, got %s", file, expected, s[file])
+		}
+	}
+}
+
+func TestExportImportCache(t *testing.T) {
+	src := setupCache(t)
+	defer os.RemoveAll(src)
+	ioutil.WriteFile(filepath.Join(src, cacheLockName), nil, 0644)
+
+	var archive bytes.Buffer
+	if err := ExportCache(src, &archive); err != nil {
+		t.Fatal(err)
+	}
+	dest, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	archivePath := filepath.Join(dest, "export.tar.gz")
+	if err := ioutil.WriteFile(archivePath, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//The destination already has its own, different, index
+	base := filepath.Join(dest, "v1")
+	os.MkdirAll(base, 0750)
+	ioutil.WriteFile(filepath.Join(base, "cpp.json"), []byte(`[{"path":"zoo","sha":"new"}]`), 0644)
+
+	res, err := ImportCache(base, archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//3 tarballs, 2 digests
+	if res.Added != 5 || res.Snapshots != 1 || res.Skipped != 0 {
+		t.Errorf("unexpected import %+v", res)
+	}
+	if FileExists(filepath.Join(base, cacheLockName)) {
+		t.Errorf("the lock must not be imported")
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(base, "cpp.json")); string(b) != `[{"path":"zoo","sha":"new"}]` {
+		t.Errorf("the local index must be kept, got %s", b)
+	}
+	cached, _ := ListCachedSamples(base)
+	referenced := 0
+	for _, c := range cached {
+		if c.Referenced {
+			referenced++
+		}
+	}
+	if len(cached) != 3 || referenced != 2 {
+		t.Errorf("imported versions should be known through the snapshot, got %d cached %d referenced", len(cached), referenced)
+	}
+
+	res, err = ImportCache(base, archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Added != 0 || res.Snapshots != 0 || res.Skipped != 6 {
+		t.Errorf("importing again should change nothing, got %+v", res)
+	}
+
+	if FileExists(filepath.Join(base, importLockName)) {
+		t.Errorf("the import lock must be released")
+	}
+
+	//Not while another CLI imports
+	ioutil.WriteFile(filepath.Join(base, importLockName), nil, 0644)
+	if _, err := ImportCache(base, archivePath); err != ErrCacheBusy {
+		t.Errorf("expected importing during another import to fail, got %v", err)
+	}
+	if info, _ := CacheStats(base); info == nil || info.Locked || !info.Importing {
+		t.Errorf("an import must not report the cache as corrupt, got %+v", info)
+	}
+	os.Remove(filepath.Join(base, importLockName))
+
+	//Nor into a corrupt cache
+	ioutil.WriteFile(filepath.Join(base, cacheLockName), nil, 0644)
+	if _, err := ImportCache(base, archivePath); err != ErrCacheLock {
+		t.Errorf("expected importing into a locked cache to fail, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dataDirName holds the data files fetched from the aggregator, other than
+// the indexes of the languages
+const dataDirName = "data"
+
+// Data files the aggregator may serve next to the indexes
+const (
+	CompilersDataFile       = "compilers.json"        // the compiler table, see deps.LoadCompilers
+	ComponentsDataFile      = "compmapping.json"      // the directories of the components of the oneAPI root
+	SuiteComponentsDataFile = "suite-components.json" // the toolkits each component comes with
+	SuitesDataFile          = "suites.json"           // the toolkits
+	DistroPackagesDataFile  = "distro-packages.json"  // the distribution packages of pkg-config packages
+)
+
+// dataFiles are fetched from the aggregator with the indexes
+var dataFiles = []string{CompilersDataFile, ComponentsDataFile, SuiteComponentsDataFile, SuitesDataFile, DistroPackagesDataFile}
+
+var dataValidators struct {
+	sync.Mutex
+	byName map[string]func([]byte) error
+}
+
+// ValidateDataFile has a data file checked by validate when it is fetched,
+// a version it rejects is not kept and the copy fetched before stays. Every
+// data file must be a JSON array.
+func ValidateDataFile(name string, validate func([]byte) error) {
+	dataValidators.Lock()
+	defer dataValidators.Unlock()
+	if dataValidators.byName == nil {
+		dataValidators.byName = make(map[string]func([]byte) error)
+	}
+	dataValidators.byName[name] = validate
+}
+
+// validateDataFile checks a data file served by the aggregator
+func validateDataFile(name string, b []byte) error {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return fmt.Errorf("it is not a JSON array")
+	}
+	dataValidators.Lock()
+	validate := dataValidators.byName[name]
+	dataValidators.Unlock()
+	if validate == nil {
+		return nil
+	}
+	return validate(b)
+}
+
+// DataFilePath is where a data file of the aggregator is kept in the cache
+// at base
+func DataFilePath(base string, name string) string {
+	return filepath.Join(base, dataDirName, name)
+}
+
+// dataHistoryKey is where the snapshots of a data file are kept, next to
+// those of the indexes
+func dataHistoryKey(name string) string {
+	return filepath.Join(dataDirName, strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// DataSnapshots lists the versions of a data file that have been seen,
+// newest first
+func (a *Aggregator) DataSnapshots(name string) ([]Snapshot, error) {
+	return snapshots(a.localPath, dataHistoryKey(name))
+}
+
+// syncDataFiles fetches the data files of the aggregator, versioned like the
+// indexes. Not every aggregator serves them, when one can not be fetched the
+// copy fetched before, if any, is kept.
+func (a *Aggregator) syncDataFiles() error {
+	for _, name := range dataFiles {
+		remoteHash, remote, err := sha512URL(a.baseURL.String() + "/" + name)
+		if err != nil {
+			continue
+		}
+		if err := validateDataFile(name, remote); err != nil {
+			log.Printf("ignoring %s served by the sample aggregator - %v\n", name, err)
+			continue
+		}
+		p := DataFilePath(a.localPath, name)
+		if FileExists(p) {
+			local, err := localHash(p)
+			if err != nil {
+				return err
+			}
+			//Keep the copy being replaced, as of when it was fetched
+			if !bytes.Equal(remoteHash, local) {
+				if err := snapshotFile(a.localPath, dataHistoryKey(name), p); err != nil {
+					return err
+				}
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, remote, 0644); err != nil {
+			return err
+		}
+		if err := writeSnapshot(a.localPath, dataHistoryKey(name), remote, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package aggregator
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestSyncDataFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneapi-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := `[{"name":"icx","paths":{"linux":["bin/icx"]}}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cpp.json":
+			fmt.Fprintln(w, testJSONdate)
+		case "/" + CompilersDataFile:
+			fmt.Fprint(w, table)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	if _, err := NewAggregator(ts.URL, dir, []string{"cpp"}, false, false); err != nil {
+		t.Fatal(err)
+	}
+	p := DataFilePath(CacheDir(dir), CompilersDataFile)
+	b, err := ioutil.ReadFile(p)
+	if err != nil || string(b) != table {
+		t.Fatalf("expected the compiler table in %s, got %q - %v", p, b, err)
+	}
+	languages, err := cacheLanguages(CacheDir(dir))
+	if err != nil || len(languages) != 1 || languages[0] != "cpp" {
+		t.Errorf("cache languages are %v, expected only cpp - %v", languages, err)
+	}
+
+	//A new version replaces the copy, keeping the one before in the history
+	first := table
+	table = `[{"name":"icx","paths":{"linux":["bin/compiler/icx"]}}]`
+	a, err := NewAggregator(ts.URL, dir, []string{"cpp"}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(p); string(b) != table {
+		t.Errorf("expected the new compiler table, got %q", b)
+	}
+	snaps, err := a.DataSnapshots(CompilersDataFile)
+	if err != nil || len(snaps) != 2 {
+		t.Fatalf("expected 2 versions of the compiler table, got %v - %v", snaps, err)
+	}
+	if b, _ := ioutil.ReadFile(snaps[1].Path); string(b) != first {
+		t.Errorf("expected the first compiler table in the history, got %q", b)
+	}
+	removed, err := a.ApplyRetention(Retention{Snapshots: 1})
+	if err != nil || len(removed) != 1 || removed[0] != snaps[1].Path {
+		t.Errorf("expected the first compiler table to be removed, got %v - %v", removed, err)
+	}
+
+	//Not JSON, the wrong shape, rejected or not served at all keeps the copy
+	//fetched before
+	ValidateDataFile(CompilersDataFile, func(b []byte) error {
+		if bytes.Contains(b, []byte("/etc")) {
+			return fmt.Errorf("absolute path")
+		}
+		return nil
+	})
+	defer ValidateDataFile(CompilersDataFile, nil)
+	kept := table
+	for _, table = range []string{"<html>", `{"name":"icx"}`, `[{"name":"icx","paths":{"linux":["/etc/icx"]}}]`} {
+		if _, err := NewAggregator(ts.URL, dir, []string{"cpp"}, false, false); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := ioutil.ReadFile(p); string(b) != kept {
+			t.Errorf("compiler table was replaced by %q", b)
+		}
+	}
+	if _, err := os.Stat(DataFilePath(CacheDir(dir), SuitesDataFile)); !os.IsNotExist(err) {
+		t.Errorf("suites are not served, expected no copy - %v", err)
+	}
+}
@@ -5,8 +5,11 @@ package aggregator
 
 import (
 	"crypto/sha512"
+	"encoding/hex"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 //FileExists helper function for checking a file exists
@@ -31,3 +34,34 @@ func localHash(path string) ([]byte, error) {
 	}
 	return hasher.Sum(nil), nil
 }
+
+//FileDigest returns the hex sha512 of a file, as recorded in lockfiles
+func FileDigest(path string) (string, error) {
+	h, err := localHash(path)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h), nil
+}
+
+//copyFile copies src to dest, creating the directory of dest
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
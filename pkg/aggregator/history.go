@@ -0,0 +1,332 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyDirName holds a snapshot of every index seen, per language
+const historyDirName = "history"
+
+// Snapshot is a version of a language index that has been seen
+type Snapshot struct {
+	Language string    `json:"language"`
+	Version  string    `json:"version"` // sha512 of the index, as IndexVersion
+	Seen     time.Time `json:"seen"`    // last time it was the current index
+	Path     string    `json:"path"`
+}
+
+// SampleVersion is a version of a sample that has been seen in an index or is in the cache
+type SampleVersion struct {
+	SHA     string    `json:"sha"`
+	Seen    time.Time `json:"seen"`
+	Current bool      `json:"current,omitempty"`
+	Cached  bool      `json:"cached,omitempty"`
+	Sample  Sample    `json:"-"` // as described by the newest index holding it
+}
+
+// Retention bounds the disk used by older versions, zero keeps everything
+type Retention struct {
+	Versions  int // tarballs kept per sample, including the current one
+	Snapshots int // index snapshots kept per language, including the current one
+}
+
+// DefaultRetention is used unless configured otherwise
+var DefaultRetention = Retention{Versions: 3, Snapshots: 10}
+
+func historyDir(base string, language string) string {
+	return filepath.Join(base, historyDirName, language)
+}
+
+// snapshotIndex records an index as seen at the passed time
+func (a *Aggregator) snapshotIndex(language string, index []byte, seen time.Time) error {
+	return writeSnapshot(a.localPath, language, index, seen)
+}
+
+func writeSnapshot(base string, language string, index []byte, seen time.Time) error {
+	sum := sha512.Sum512(index)
+	path := filepath.Join(historyDir(base, language), hex.EncodeToString(sum[:])+".json")
+	if !FileExists(path) {
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, index, 0644); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(path, seen, seen)
+}
+
+// Snapshots lists the index snapshots of a language, newest first
+func (a *Aggregator) Snapshots(language string) ([]Snapshot, error) {
+	return snapshots(a.localPath, language)
+}
+
+func snapshots(base string, language string) ([]Snapshot, error) {
+	infos, err := ioutil.ReadDir(historyDir(base, language))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snaps []Snapshot
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		snaps = append(snaps, Snapshot{
+			Language: language,
+			Version:  strings.TrimSuffix(info.Name(), ".json"),
+			Seen:     info.ModTime(),
+			Path:     filepath.Join(historyDir(base, language), info.Name()),
+		})
+	}
+	sort.SliceStable(snaps, func(i, j int) bool {
+		return snaps[i].Seen.After(snaps[j].Seen)
+	})
+	return snaps, nil
+}
+
+// Samples reads the samples of a snapshot, unfiltered
+func (s Snapshot) Samples() ([]Sample, error) {
+	b, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var samples []Sample
+	return samples, json.Unmarshal(b, &samples)
+}
+
+// Versions lists every known version of a sample, newest first. Versions come
+// from the current index, the index snapshots and the tarballs in the cache.
+func (a *Aggregator) Versions(language string, path string) ([]SampleVersion, error) {
+	bySHA := make(map[string]*SampleVersion)
+	var order []string
+	add := func(s Sample, seen time.Time) *SampleVersion {
+		v, ok := bySHA[s.SHA]
+		if !ok {
+			v = &SampleVersion{SHA: s.SHA, Seen: seen, Sample: s}
+			bySHA[s.SHA] = v
+			order = append(order, s.SHA)
+		}
+		return v
+	}
+
+	current, hasCurrent := a.FindSample(language, path)
+	if hasCurrent {
+		add(current, time.Now()).Current = true
+	}
+
+	snaps, err := a.Snapshots(language)
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snaps {
+		samples, err := snap.Samples()
+		if err != nil {
+			continue // a damaged snapshot only loses history
+		}
+		for _, s := range samples {
+			if s.Path == path && s.SHA != "" {
+				add(s, snap.Seen)
+			}
+		}
+	}
+
+	cached := a.cachedVersions(a.localPath, language, path)
+	if a.systemPath != "" {
+		for sha, tarPath := range a.cachedVersions(a.systemPath, language, path) {
+			if _, ok := cached[sha]; !ok {
+				cached[sha] = tarPath
+			}
+		}
+	}
+	for sha, tarPath := range cached {
+		v := bySHA[sha]
+		if v == nil {
+			info, err := os.Stat(tarPath)
+			if err != nil {
+				continue
+			}
+			v = add(Sample{Path: path, SHA: sha}, info.ModTime())
+		}
+		v.Cached = true
+	}
+
+	versions := make([]SampleVersion, 0, len(order))
+	for _, sha := range order {
+		versions = append(versions, *bySHA[sha])
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Current != versions[j].Current {
+			return versions[i].Current
+		}
+		return versions[i].Seen.After(versions[j].Seen)
+	})
+	return versions, nil
+}
+
+// cachedVersions maps the SHA of every tarball of a sample cached in base to its path
+func (a *Aggregator) cachedVersions(base string, language string, path string) map[string]string {
+	cached := make(map[string]string)
+	infos, err := ioutil.ReadDir(filepath.Join(base, language, path))
+	if err != nil {
+		return cached
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		//a nested sample, i.e. a/b inside of a, is not a version of a
+		if _, nested := a.FindSample(language, path+"/"+info.Name()); nested {
+			continue
+		}
+		if tarPath, ok := CachedTarBall(base, language, path, info.Name()); ok {
+			cached[info.Name()] = tarPath
+		}
+	}
+	return cached
+}
+
+// ApplyRetention removes the oldest index snapshots and cached tarballs beyond
+// the policy. The current index, the current version of every sample and
+// the pinned versions, see Pin, are always kept. The removed files are returned.
+func (a *Aggregator) ApplyRetention(r Retention) ([]string, error) {
+	var removed []string
+	pins, err := ReadPins(a.localPath)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[string]bool)
+	for _, p := range pins {
+		pinned[p.key()] = true
+	}
+	for _, language := range a.languages {
+		snaps, err := a.Snapshots(language)
+		if err != nil {
+			return removed, err
+		}
+		current, _ := a.IndexVersion(language)
+
+		if r.Versions > 0 {
+			paths := make(map[string]bool)
+			for _, s := range a.Samples[language] {
+				paths[s.Path] = true
+			}
+			for _, snap := range snaps {
+				samples, _ := snap.Samples()
+				for _, s := range samples {
+					paths[s.Path] = true
+				}
+			}
+			for path := range paths {
+				rm, err := a.pruneVersions(language, path, r.Versions, pinned)
+				removed = append(removed, rm...)
+				if err != nil {
+					return removed, err
+				}
+			}
+		}
+
+		rm, err := pruneSnapshots(snaps, current, r.Snapshots)
+		removed = append(removed, rm...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	for _, name := range dataFiles {
+		snaps, err := snapshots(a.localPath, dataHistoryKey(name))
+		if err != nil {
+			return removed, err
+		}
+		current, _ := localHash(DataFilePath(a.localPath, name))
+		rm, err := pruneSnapshots(snaps, hex.EncodeToString(current), r.Snapshots)
+		removed = append(removed, rm...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// pruneSnapshots keeps the current and the newest snapshots, newest first as
+// listed by snapshots
+func pruneSnapshots(snaps []Snapshot, current string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	var removed []string
+	kept := 0
+	for _, snap := range snaps {
+		if snap.Version == current || kept < keep-1 {
+			if snap.Version != current {
+				kept++
+			}
+			continue
+		}
+		if err := os.Remove(snap.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, snap.Path)
+	}
+	return removed, nil
+}
+
+// pruneVersions keeps the newest and the pinned cached tarballs of a sample,
+// the system cache is never touched
+func (a *Aggregator) pruneVersions(language string, path string, keep int, pinned map[string]bool) ([]string, error) {
+	versions, err := a.Versions(language, path)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	kept := 0
+	for _, v := range versions {
+		tarPath, ok := CachedTarBall(a.localPath, language, path, v.SHA)
+		if !ok {
+			continue
+		}
+		if pinned[Pin{Language: language, Path: path, SHA: v.SHA}.key()] {
+			continue
+		}
+		if v.Current || kept < keep-1 {
+			if !v.Current {
+				kept++
+			}
+			continue
+		}
+		if err := os.RemoveAll(filepath.Dir(tarPath)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, tarPath)
+	}
+	return removed, nil
+}
+
+// snapshotFile records an index file as seen when it was last written
+func (a *Aggregator) snapshotFile(language string, path string) error {
+	return snapshotFile(a.localPath, language, path)
+}
+
+func snapshotFile(base string, language string, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	index, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return writeSnapshot(base, language, index, info.ModTime())
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package aggregator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexSnapshots(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+
+	index := `[{"path":"zoo","sha":"aaa","example":{"name":"zoo"}}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, index)
+	}))
+	defer ts.Close()
+
+	a, err := NewAggregator(ts.URL, td.dir, td.testLanguages, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetSampleTarBall(a.GetLocalPath(), ts.URL, "cpp", a.Samples["cpp"][0]); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := a.IndexVersion("cpp")
+	if a.Updated {
+		t.Errorf("a first index replaces nothing")
+	}
+
+	//The aggregator publishes a new version of the sample
+	index = `[{"path":"zoo","sha":"bbb","example":{"name":"zoo"}}]`
+	a, err = NewAggregator(ts.URL, td.dir, td.testLanguages, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := a.IndexVersion("cpp")
+	if !a.Updated {
+		t.Errorf("expected the new index to be reported")
+	}
+
+	snaps, err := a.Snapshots("cpp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 || snaps[0].Version != second || snaps[1].Version != first {
+		t.Fatalf("expected snapshots of both indexes, newest first, got %v", snaps)
+	}
+
+	versions, err := a.Versions("cpp", "zoo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %v", versions)
+	}
+	if versions[0].SHA != "bbb" || !versions[0].Current || versions[0].Cached {
+		t.Errorf("expected the current uncached version first, got %+v", versions[0])
+	}
+	if versions[1].SHA != "aaa" || versions[1].Current || !versions[1].Cached {
+		t.Errorf("expected the old cached version second, got %+v", versions[1])
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := &Aggregator{localPath: dir, languages: []string{"cpp"}, Samples: Samples{"cpp": {{Path: "zoo", SHA: "v1"}}}}
+
+	//v1 is current even though it is the oldest, v4 is cached but never indexed
+	now := time.Now()
+	for i, sha := range []string{"v1", "v2", "v3", "v4"} {
+		tarPath, _ := versionedTarBallPath(dir, "cpp", "zoo", sha)
+		os.MkdirAll(filepath.Dir(tarPath), 0750)
+		if err := ioutil.WriteFile(tarPath, []byte(sha), 0644); err != nil {
+			t.Fatal(err)
+		}
+		seen := now.Add(time.Duration(i-10) * time.Hour)
+		os.Chtimes(tarPath, seen, seen)
+		if sha != "v4" {
+			index := fmt.Sprintf(`[{"path":"zoo","sha":"%s"}]`, sha)
+			if err := a.snapshotIndex("cpp", []byte(index), seen); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	//a nested sample is not a version
+	a.Samples["cpp"] = append(a.Samples["cpp"], Sample{Path: "zoo/nested", SHA: "x"})
+	os.MkdirAll(filepath.Join(dir, "cpp", "zoo", "nested"), 0750)
+	ioutil.WriteFile(filepath.Join(dir, "cpp", "zoo", "nested", "cpp.tar.gz"), nil, 0644)
+	//the current index
+	ioutil.WriteFile(filepath.Join(dir, "cpp.json"), []byte(`[{"path":"zoo","sha":"v1"}]`), 0644)
+	//a project uses v2
+	if err := a.Pin(Pin{Language: "cpp", Path: "zoo", SHA: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := a.ApplyRetention(Retention{Versions: 2, Snapshots: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for sha, kept := range map[string]bool{"v1": true, "v2": true, "v3": false, "v4": true} {
+		if _, ok := CachedTarBall(dir, "cpp", "zoo", sha); ok != kept {
+			t.Errorf("%s: expected kept=%v", sha, kept)
+		}
+	}
+	if !FileExists(filepath.Join(dir, "cpp", "zoo", "nested", "cpp.tar.gz")) {
+		t.Errorf("a nested sample must not be pruned")
+	}
+	snaps, _ := a.Snapshots("cpp")
+	if len(snaps) != 2 {
+		t.Errorf("expected 2 snapshots kept, got %d", len(snaps))
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 1 tarball and 1 snapshot removed, got %v", removed)
+	}
+
+	if removed, _ := a.ApplyRetention(Retention{}); len(removed) != 0 {
+		t.Errorf("an empty policy should keep everything, removed %v", removed)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalIndexSuffix names the index of the samples sideloaded into the cache
+// for a language, <language>.local.json next to the index of the
+// aggregator. It is never synced, its samples are merged into the index and
+// replace a sample of the aggregator with the same path.
+const LocalIndexSuffix = ".local.json"
+
+func localIndexPath(base string, language string) string {
+	return filepath.Join(base, language+LocalIndexSuffix)
+}
+
+// readLocalIndex reads the sideloaded samples of a language, there may be none
+func readLocalIndex(base string, language string) ([]Sample, error) {
+	b, err := ioutil.ReadFile(localIndexPath(base, language))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var samples []Sample
+	if err := json.Unmarshal(b, &samples); err != nil {
+		return nil, fmt.Errorf("invalid local index %s - %v", localIndexPath(base, language), err)
+	}
+	for i := range samples {
+		samples[i].Local = true
+	}
+	return samples, nil
+}
+
+// mergeLocal adds the sideloaded samples to the samples of an index
+func mergeLocal(samples []Sample, local []Sample) []Sample {
+	if len(local) == 0 {
+		return samples
+	}
+	byPath := make(map[string]bool)
+	for _, s := range local {
+		byPath[s.Path] = true
+	}
+	var merged []Sample
+	for _, s := range samples {
+		if !byPath[s.Path] {
+			merged = append(merged, s)
+		}
+	}
+	return append(merged, local...)
+}
+
+// AddLocalSample sideloads the tarball at tarPath into the cache at base as
+// the sample s of language and records s in the local index, replacing an
+// earlier sample with the same path. Without a SHA one is made up from the
+// digest of the tarball. The sample as recorded and the path of the tarball
+// in the cache are returned.
+func AddLocalSample(base string, language string, s Sample, tarPath string) (Sample, string, error) {
+	if !shaReg.MatchString(language) || strings.HasSuffix(language, ".local") || language == historyDirName || language == dataDirName {
+		return s, "", fmt.Errorf("'%s' is not a valid language", language)
+	}
+	s.Path = strings.Trim(filepath.ToSlash(s.Path), "/")
+	if s.Path == "" || path.Clean(s.Path) != s.Path || s.Path == ".." || strings.HasPrefix(s.Path, "../") {
+		return s, "", fmt.Errorf("'%s' is not a valid sample path", s.Path)
+	}
+	if err := readTarGz(tarPath); err != nil {
+		return s, "", fmt.Errorf("%s is not a sample tarball - %v", tarPath, err)
+	}
+	if s.SHA == "" {
+		digest, err := FileDigest(tarPath)
+		if err != nil {
+			return s, "", err
+		}
+		s.SHA = "local-" + digest[:12]
+	}
+	if s.Fields.Name == "" {
+		s.Fields.Name = path.Base(s.Path)
+	}
+	s.Local = true
+
+	dest, ok := versionedTarBallPath(base, language, s.Path, s.SHA)
+	if !ok {
+		return s, "", fmt.Errorf("'%s' is not a valid SHA", s.SHA)
+	}
+	local, err := readLocalIndex(base, language)
+	if err != nil {
+		return s, "", err
+	}
+
+	if err := copyFile(tarPath, dest); err != nil {
+		os.Remove(dest)
+		return s, "", err
+	}
+	if err := recordDigest(dest); err != nil {
+		return s, "", err
+	}
+
+	local = mergeLocal(local, []Sample{s})
+	b, err := json.MarshalIndent(local, "", "\t")
+	if err != nil {
+		return s, "", err
+	}
+	return s, dest, ioutil.WriteFile(localIndexPath(base, language), append(b, '\n'), 0644)
+}
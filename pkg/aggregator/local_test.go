@@ -0,0 +1,109 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package aggregator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddLocalSample(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+	base := CacheDir(td.dir)
+
+	tarPath := filepath.Join(td.dir, "handed-over.tar.gz")
+	writeTestTarGz(t, tarPath, "local")
+	ioutil.WriteFile(filepath.Join(td.dir, "junk.tar.gz"), []byte("junk"), 0644)
+
+	for _, tc := range []struct {
+		language, path, tar string
+	}{
+		{"cpp", "", tarPath},
+		{"cpp", "../escape", tarPath},
+		{"cpp", "a/../b", tarPath},
+		{"../cpp", "mine", tarPath},
+		{"cpp.local", "mine", tarPath},
+		{"cpp", "mine", filepath.Join(td.dir, "junk.tar.gz")},
+	} {
+		if _, _, err := AddLocalSample(base, tc.language, Sample{Path: tc.path}, tc.tar); err == nil {
+			t.Errorf("expected sideloading %s %s from %s to fail", tc.language, tc.path, tc.tar)
+		}
+	}
+
+	s, cached, err := AddLocalSample(base, "cpp", Sample{Path: "my/sample"}, tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Local || !strings.HasPrefix(s.SHA, "local-") || s.Fields.Name != "sample" {
+		t.Errorf("unexpected sample recorded %+v", s)
+	}
+	if c, ok := CachedTarBall(base, "cpp", "my/sample", s.SHA); !ok || c != cached {
+		t.Errorf("expected the tarball in the cache at %s, got %s", c, cached)
+	}
+	if r := verifyTarBall(cached); r.Status != VerifyOK {
+		t.Errorf("expected the digest of the tarball to be recorded, got %+v", r)
+	}
+
+	//Sideloading the same path again replaces the entry
+	entry := Sample{Path: "testrepo/simple-test-test", SHA: "dev", Fields: Fields{Name: "Mine", Description: "work in progress"}}
+	if _, _, err := AddLocalSample(base, "cpp", entry, tarPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := AddLocalSample(base, "cpp", entry, tarPath); err != nil {
+		t.Fatal(err)
+	}
+	local, err := readLocalIndex(base, "cpp")
+	if err != nil || len(local) != 2 {
+		t.Fatalf("expected 2 sideloaded samples, got %v %v", local, err)
+	}
+
+	//Merged with the index, replacing the sample of the aggregator
+	a, err := NewAggregator(td.ts.URL, td.dir, td.testLanguages, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Samples["cpp"]) != 2 {
+		t.Fatalf("expected the sideloaded samples merged into the index, got %v", a.Samples["cpp"])
+	}
+	mine, ok := a.FindSample("cpp", "testrepo/simple-test-test")
+	if !ok || !mine.Local || mine.Fields.Name != "Mine" {
+		t.Errorf("expected the sideloaded sample to replace the one of the aggregator, got %+v", mine)
+	}
+	if got, err := a.SampleTarBall("cpp", mine); err != nil || filepath.Dir(got) != filepath.Join(base, "cpp", "testrepo", "simple-test-test", "dev") {
+		t.Errorf("expected the sideloaded tarball, got %s %v", got, err)
+	}
+
+	//The cache knows it is not a language of its own and keeps the tarballs
+	languages, _ := cacheLanguages(base)
+	if !reflect.DeepEqual(languages, []string{"cpp"}) {
+		t.Errorf("expected only cpp in the cache, got %v", languages)
+	}
+	removed, err := Prune(base, 0, false)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("sideloaded samples should not be pruned, removed %v %v", removed, err)
+	}
+	//however old, they can not be fetched again
+	aged := time.Now().Add(-48 * time.Hour)
+	dev, _ := CachedTarBall(base, "cpp", "testrepo/simple-test-test", "dev")
+	for _, p := range []string{cached, dev} {
+		os.Chtimes(p, aged, aged)
+	}
+	removed, err = Prune(base, 24*time.Hour, false)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("aged sideloaded samples should not be pruned, removed %v %v", removed, err)
+	}
+
+	//A sideloaded sample missing from the cache is not downloaded from the aggregator
+	if err := os.Remove(dev); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.SampleTarBall("cpp", mine); err == nil || FileExists(dev) {
+		t.Errorf("expected a missing sideloaded tarball to fail, got %s", got)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheLayout is the layout of the cache directory written by this CLI.
+// Changes to the layout are migrations rather than a new
+// AggregatorLocalAPILevel, so existing caches are upgraded in place.
+//
+//	1 - <lang>.json and <lang>/<path>/<lang>.tar.gz, before the layout was recorded
+//	2 - tarballs kept per SHA with their digest, snapshots of every index seen
+const CacheLayout = 2
+
+// cacheMetaName holds the CacheMeta of a cache. It is not named .json so it
+// is never mistaken for the index of a language.
+const cacheMetaName = "cache.meta"
+
+// CacheMeta describes a cache directory
+type CacheMeta struct {
+	Layout   int       `json:"layout"`
+	Migrated time.Time `json:"migrated,omitempty"` // last time a migration was applied
+}
+
+// migration upgrades a cache from layout from to layout from+1. It must be
+// safe to run again should an earlier attempt have been interrupted.
+type migration struct {
+	from        int
+	description string
+	run         func(base string) error
+}
+
+// migrations are applied in order, one layout at a time
+var migrations = []migration{
+	{1, "record index snapshots and tarball digests", migrateRecordHistory},
+}
+
+// ReadCacheMeta reads the metadata of the cache at base. A cache without
+// metadata predates it and is layout 1, unless it is empty.
+func ReadCacheMeta(base string) (CacheMeta, error) {
+	return readCacheMeta(base, CacheLayout)
+}
+
+// readCacheMeta is ReadCacheMeta with a new cache at layout current
+func readCacheMeta(base string, current int) (CacheMeta, error) {
+	b, err := ioutil.ReadFile(filepath.Join(base, cacheMetaName))
+	if os.IsNotExist(err) {
+		infos, err := ioutil.ReadDir(base)
+		if err != nil && !os.IsNotExist(err) {
+			return CacheMeta{}, err
+		}
+		if len(infos) == 0 {
+			return CacheMeta{Layout: current}, nil
+		}
+		return CacheMeta{Layout: 1}, nil
+	}
+	if err != nil {
+		return CacheMeta{}, err
+	}
+	var m CacheMeta
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("invalid cache metadata %s - %v", filepath.Join(base, cacheMetaName), err)
+	}
+	return m, nil
+}
+
+func writeCacheMeta(base string, m CacheMeta) error {
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(base, cacheMetaName), append(b, '\n'), 0644)
+}
+
+// MigrateCache upgrades the cache at base to CacheLayout, recording the
+// layout after every step so an interrupted upgrade resumes where it
+// stopped. The descriptions of the applied migrations are returned.
+func MigrateCache(base string) ([]string, error) {
+	return migrateTo(base, CacheLayout)
+}
+
+func migrateTo(base string, layout int) ([]string, error) {
+	if !FileExists(base) {
+		return nil, nil // nothing cached yet
+	}
+	m, err := readCacheMeta(base, layout)
+	if err != nil {
+		return nil, err
+	}
+	if m.Layout > layout {
+		return nil, fmt.Errorf("the cache in %s was written by a newer CLI (layout %d, this CLI knows %d), please update or use another directory", base, m.Layout, layout)
+	}
+	if m.Layout == layout {
+		if !FileExists(filepath.Join(base, cacheMetaName)) {
+			return nil, writeCacheMeta(base, m)
+		}
+		return nil, nil
+	}
+
+	var applied []string
+	for _, step := range migrations {
+		if step.from != m.Layout {
+			continue
+		}
+		if err := step.run(base); err != nil {
+			return applied, fmt.Errorf("failed to upgrade the cache in %s to layout %d (%s) - %v", base, step.from+1, step.description, err)
+		}
+		m.Layout = step.from + 1
+		m.Migrated = time.Now().UTC()
+		if err := writeCacheMeta(base, m); err != nil {
+			return applied, err
+		}
+		applied = append(applied, step.description)
+	}
+	if m.Layout != layout {
+		return applied, fmt.Errorf("no migration of the cache in %s from layout %d", base, m.Layout)
+	}
+	return applied, nil
+}
+
+// migrateRecordHistory keeps the current indexes as snapshots, so their
+// versions stay known once they are replaced, and records the digest of the
+// cached tarballs. Tarballs of the old layout stay where they are, their
+// SHA is not known.
+func migrateRecordHistory(base string) error {
+	languages, err := cacheLanguages(base)
+	if err != nil {
+		return err
+	}
+	for _, language := range languages {
+		index := filepath.Join(base, language+".json")
+		if !FileExists(index) {
+			continue
+		}
+		if err := snapshotFile(base, language, index); err != nil {
+			return err
+		}
+	}
+
+	cached, err := ListCachedSamples(base)
+	if err != nil {
+		return err
+	}
+	for _, c := range cached {
+		if FileExists(c.File + DigestSuffix) {
+			continue
+		}
+		if err := recordDigest(c.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package aggregator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setupV1Cache builds a cache as written before the layout was recorded
+func setupV1Cache(t *testing.T) string {
+	t.Helper()
+	base, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(base, "cpp.json"), []byte(`[{"path":"zoo","sha":"aaa"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestTarGz(t, filepath.Join(base, "cpp", "zoo", "cpp.tar.gz"), "legacy")
+	return base
+}
+
+func TestReadCacheMeta(t *testing.T) {
+	empty, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(empty)
+	if m, err := ReadCacheMeta(empty); err != nil || m.Layout != CacheLayout {
+		t.Errorf("a new cache should be the current layout, got %v %v", m, err)
+	}
+
+	old := setupV1Cache(t)
+	defer os.RemoveAll(old)
+	if m, err := ReadCacheMeta(old); err != nil || m.Layout != 1 {
+		t.Errorf("a cache without metadata should be layout 1, got %v %v", m, err)
+	}
+
+	ioutil.WriteFile(filepath.Join(old, cacheMetaName), []byte("{"), 0644)
+	if _, err := ReadCacheMeta(old); err == nil {
+		t.Errorf("broken metadata should be an error")
+	}
+}
+
+func TestMigrateRecordHistory(t *testing.T) {
+	base := setupV1Cache(t)
+	defer os.RemoveAll(base)
+
+	if err := migrateRecordHistory(base); err != nil {
+		t.Fatal(err)
+	}
+	snaps, err := snapshots(base, "cpp")
+	if err != nil || len(snaps) != 1 {
+		t.Fatalf("expected the index to be kept as a snapshot, got %v %v", snaps, err)
+	}
+	legacy := filepath.Join(base, "cpp", "zoo", "cpp.tar.gz")
+	if r := verifyTarBall(legacy); r.Status != VerifyOK {
+		t.Errorf("expected the tarball digest to be recorded, got %+v", r)
+	}
+
+	//Running again, as after an interruption, changes nothing
+	if err := migrateRecordHistory(base); err != nil {
+		t.Fatal(err)
+	}
+	if snaps, _ := snapshots(base, "cpp"); len(snaps) != 1 {
+		t.Errorf("expected a single snapshot after running again, got %d", len(snaps))
+	}
+}
+
+func TestMigrateCache(t *testing.T) {
+	defer func(m []migration) { migrations = m }(migrations)
+
+	base := setupV1Cache(t)
+	defer os.RemoveAll(base)
+
+	//Pretend there are more layouts, the third step fails once
+	var ran []int
+	fail := true
+	migrations = []migration{
+		{1, "one", func(string) error { ran = append(ran, 1); return nil }},
+		{2, "two", func(string) error { ran = append(ran, 2); return nil }},
+		{3, "three", func(string) error {
+			ran = append(ran, 3)
+			if fail {
+				return fmt.Errorf("interrupted")
+			}
+			return nil
+		}},
+	}
+	upgrade := func(target int) ([]string, error) {
+		t.Helper()
+		return migrateTo(base, target)
+	}
+
+	applied, err := upgrade(4)
+	if err == nil {
+		t.Fatalf("the failing step should fail the migration")
+	}
+	if !reflect.DeepEqual(applied, []string{"one", "two"}) {
+		t.Errorf("expected the first two steps applied, got %v", applied)
+	}
+	if m, _ := ReadCacheMeta(base); m.Layout != 3 {
+		t.Errorf("expected the completed steps to be recorded, got layout %d", m.Layout)
+	}
+
+	fail = false
+	ran = nil
+	applied, err = upgrade(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ran, []int{3}) || !reflect.DeepEqual(applied, []string{"three"}) {
+		t.Errorf("expected to resume with the third step, ran %v", ran)
+	}
+
+	ran = nil
+	if applied, err := upgrade(4); err != nil || len(applied) != 0 || len(ran) != 0 {
+		t.Errorf("an up to date cache should not be migrated, ran %v", ran)
+	}
+	if _, err := upgrade(3); err == nil {
+		t.Errorf("a cache written by a newer CLI should be refused")
+	}
+	migrations = migrations[:1]
+	os.Remove(filepath.Join(base, cacheMetaName))
+	if _, err := upgrade(4); err == nil {
+		t.Errorf("a missing migration should be an error")
+	}
+}
+
+func TestNewAggregatorMigrates(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+
+	base := filepath.Join(td.dir, AggregatorLocalAPILevel)
+	os.MkdirAll(filepath.Join(base, "cpp", "zoo"), 0750)
+	writeTestTarGz(t, filepath.Join(base, "cpp", "zoo", "cpp.tar.gz"), "legacy")
+
+	if _, err := NewAggregator(td.ts.URL, td.dir, td.testLanguages, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := ReadCacheMeta(base); err != nil || m.Layout != CacheLayout {
+		t.Errorf("expected the cache to be migrated to layout %d, got %v %v", CacheLayout, m, err)
+	}
+	if !FileExists(filepath.Join(base, "cpp", "zoo", "cpp.tar.gz"+DigestSuffix)) {
+		t.Errorf("expected the migration to record the tarball digest")
+	}
+}
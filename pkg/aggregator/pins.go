@@ -0,0 +1,91 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// pinsName records the versions of samples projects and lockfiles use
+const pinsName = "pins"
+
+// Pin is a version of a sample a project or lockfile uses, retention never
+// removes it from the cache
+type Pin struct {
+	Language string `json:"language"`
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+}
+
+func (p Pin) key() string {
+	return p.Language + "/" + p.Path + "/" + p.SHA
+}
+
+var pinsMu sync.Mutex
+
+// ReadPins lists the versions pinned in the cache at base
+func ReadPins(base string) ([]Pin, error) {
+	b, err := ioutil.ReadFile(filepath.Join(base, pinsName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pins []Pin
+	return pins, json.Unmarshal(b, &pins)
+}
+
+// AddPins records versions of samples in use in the cache at base
+func AddPins(base string, pins ...Pin) error {
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+
+	existing, err := ReadPins(base)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, p := range existing {
+		seen[p.key()] = true
+	}
+	added := false
+	for _, p := range pins {
+		if p.SHA == "" || seen[p.key()] {
+			continue
+		}
+		seen[p.key()] = true
+		existing = append(existing, p)
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	sort.SliceStable(existing, func(i, j int) bool {
+		return existing[i].key() < existing[j].key()
+	})
+
+	b, err := json.MarshalIndent(existing, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(base, 0750); err != nil {
+		return err
+	}
+	tmp := filepath.Join(base, pinsName+".tmp")
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(base, pinsName))
+}
+
+// Pin records versions of samples in use in the cache
+func (a *Aggregator) Pin(pins ...Pin) error {
+	return AddPins(a.localPath, pins...)
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package aggregator
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// SystemCache returns the cache below dir when it can be used as the system
+// cache of userDir, or "" when it can not. A system cache is shared by every
+// user of a machine, i.e. populated by an administrator with
+// "oneapi-cli --directory <dir> --full-sync", and laid out like the cache of
+// a user. It is only ever read, samples missing from it are fetched into the
+// cache of the user.
+func SystemCache(dir string, userDir string) string {
+	if dir == "" {
+		return ""
+	}
+	base := CacheDir(dir)
+	if info, err := os.Stat(base); err != nil || !info.IsDir() {
+		return ""
+	}
+	if samePath(base, CacheDir(userDir)) {
+		return "" // the administrator's own cache
+	}
+	if FileExists(filepath.Join(base, cacheLockName)) {
+		log.Printf("ignoring the system cache in %s, it is locked\n", base)
+		return ""
+	}
+	if FileExists(filepath.Join(base, importLockName)) {
+		log.Printf("ignoring the system cache in %s, it is being imported into\n", base)
+		return ""
+	}
+	m, err := ReadCacheMeta(base)
+	if err != nil {
+		log.Printf("ignoring the system cache in %s - %v\n", base, err)
+		return ""
+	}
+	if m.Layout > CacheLayout {
+		log.Printf("ignoring the system cache in %s, it was written by a newer CLI (layout %d)\n", base, m.Layout)
+		return ""
+	}
+	return base
+}
+
+func samePath(a string, b string) bool {
+	clean := func(p string) string {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			p = real
+		}
+		return filepath.Clean(p)
+	}
+	return clean(a) == clean(b)
+}
+
+// GetSystemPath returns the system cache in use, "" when there is none
+func (a *Aggregator) GetSystemPath() string {
+	return a.systemPath
+}
+
+// SampleTarBall Path of the tarball for the SHA of the sample, from the system cache
+// when it has it, otherwise from the cache of the user, downloading it if needed.
+func (a *Aggregator) SampleTarBall(language string, s Sample) (string, error) {
+	if a.systemPath != "" {
+		if _, ok := versionedTarBallPath(a.systemPath, language, s.Path, s.SHA); !ok {
+			return a.TarBall(language, s.Path)
+		}
+		if tarPath, ok := CachedTarBall(a.systemPath, language, s.Path, s.SHA); ok {
+			return tarPath, nil
+		}
+	}
+	return GetSampleTarBall(a.localPath, a.baseURL.String(), language, s)
+}
+
+// TarBall Path of the tarball of a sample whose SHA is not known, as GetTarBall
+// but looking in the system cache first.
+func (a *Aggregator) TarBall(language string, path string) (string, error) {
+	if a.systemPath != "" {
+		tarPath := filepath.Join(a.systemPath, language, path, language+".tar.gz")
+		if FileExists(tarPath) && !FileExists(filepath.Join(a.localPath, language, path, language+".tar.gz")) {
+			return tarPath, nil
+		}
+	}
+	return GetTarBall(a.localPath, a.baseURL.String(), language, path)
+}
+
+// CachedTarBall Path of a previously fetched tarball for the SHA of a sample, in
+// the cache of the user or the system cache.
+func (a *Aggregator) CachedTarBall(language string, path string, sha string) (string, bool) {
+	if tarPath, ok := CachedTarBall(a.localPath, language, path, sha); ok {
+		return tarPath, true
+	}
+	if a.systemPath == "" {
+		return "", false
+	}
+	return CachedTarBall(a.systemPath, language, path, sha)
+}
+
+// copySystemIndex copies the index of a language from the system cache into
+// the cache of the user, keeping when it was fetched. It reports whether
+// there was one.
+func (a *Aggregator) copySystemIndex(language string) bool {
+	if a.systemPath == "" {
+		return false
+	}
+	src := filepath.Join(a.systemPath, language+".json")
+	info, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	dest := filepath.Join(a.localPath, language+".json")
+	if err := copyFile(src, dest); err != nil {
+		log.Printf("failed to copy the %s index of the system cache - %v\n", language, err)
+		os.Remove(dest)
+		return false
+	}
+	os.Chtimes(dest, info.ModTime(), info.ModTime())
+	return true
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package aggregator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupSystemCache builds a system cache holding the test sample
+func setupSystemCache(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := CacheDir(dir)
+	os.MkdirAll(base, 0755)
+	if err := ioutil.WriteFile(filepath.Join(base, "cpp.json"), []byte(testJSONdate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestTarGz(t, filepath.Join(base, "cpp", "testrepo", "simple-test-test", "2c755297a2073d7f317440e8429d274b284a9051", "cpp.tar.gz"), "system")
+	return dir
+}
+
+func TestSystemCache(t *testing.T) {
+	system := setupSystemCache(t)
+	defer os.RemoveAll(system)
+	user, _ := ioutil.TempDir("", "user")
+	defer os.RemoveAll(user)
+
+	if SystemCache("", user) != "" {
+		t.Errorf("no system cache was configured")
+	}
+	if SystemCache(filepath.Join(system, "nope"), user) != "" {
+		t.Errorf("a missing system cache should be ignored")
+	}
+	if SystemCache(system, system) != "" {
+		t.Errorf("the cache of the user is not a system cache")
+	}
+	if got := SystemCache(system, user); got != CacheDir(system) {
+		t.Errorf("expected the system cache %s, got '%s'", CacheDir(system), got)
+	}
+
+	writeCacheMeta(CacheDir(system), CacheMeta{Layout: CacheLayout + 1})
+	if SystemCache(system, user) != "" {
+		t.Errorf("a system cache of a newer CLI should be ignored")
+	}
+}
+
+func TestLayeredAggregator(t *testing.T) {
+	system := setupSystemCache(t)
+	defer os.RemoveAll(system)
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+	td.ts.Close()
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, ".json") {
+			fmt.Fprintln(w, testJSONdate)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	a, err := NewLayeredAggregator(ts.URL, td.dir, system, td.testLanguages, true, true)
+	if err != nil {
+		t.Fatalf("the bulk sync should be served from the system cache - %v", err)
+	}
+	s := a.Samples["cpp"][0]
+	tarPath, err := a.SampleTarBall("cpp", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(tarPath, CacheDir(system)) {
+		t.Errorf("expected the tarball of the system cache, got %s", tarPath)
+	}
+	if _, ok := CachedTarBall(a.GetLocalPath(), "cpp", s.Path, s.SHA); ok {
+		t.Errorf("the tarball should not be copied to the cache of the user")
+	}
+	if _, ok := a.CachedTarBall("cpp", s.Path, s.SHA); !ok {
+		t.Errorf("the tarball of the system cache should be cached")
+	}
+	if len(requests) != 1+len(dataFiles) {
+		t.Errorf("expected only the index and data files to be fetched, got %v", requests)
+	}
+
+	//Nothing is written to the system cache, even when a sample is missing
+	s.SHA = "bbb"
+	if _, err := a.SampleTarBall("cpp", s); err == nil {
+		t.Errorf("a sample missing from both caches should be downloaded")
+	}
+	if _, err := os.Stat(filepath.Join(CacheDir(system), "cpp", s.Path, "bbb")); !os.IsNotExist(err) {
+		t.Errorf("the system cache was written to")
+	}
+	if _, err := a.ApplyRetention(Retention{Versions: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := CachedTarBall(CacheDir(system), "cpp", s.Path, "2c755297a2073d7f317440e8429d274b284a9051"); !ok {
+		t.Errorf("retention removed a tarball of the system cache")
+	}
+}
+
+func TestLayeredAggregatorOffline(t *testing.T) {
+	system := setupSystemCache(t)
+	defer os.RemoveAll(system)
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+	td.ts.Close()
+
+	a, err := NewLayeredAggregator("http://abcIShouldNotExist.intel.com/", td.dir, system, td.testLanguages, true, false)
+	if err != nil {
+		t.Fatalf("offline the index of the system cache should be used - %v", err)
+	}
+	if len(a.Samples["cpp"]) != 1 {
+		t.Errorf("expected the sample of the system cache, got %v", a.Samples)
+	}
+	if !FileExists(filepath.Join(a.GetLocalPath(), "cpp.json")) {
+		t.Errorf("expected the index to be copied to the cache of the user")
+	}
+}
@@ -3,11 +3,15 @@
 
 package aggregator
 
+import "sort"
+
 // Sample Type
 type Sample struct {
 	Path   string `json:"path"`
 	SHA    string `json:"sha"`
 	Fields Fields `json:"example"`
+	// Local is set for samples sideloaded into the cache, see AddLocalSample
+	Local bool `json:"local,omitempty"`
 }
 
 // Fields type (nested struct in sample type)
@@ -25,8 +29,74 @@ type Fields struct {
 	Builder      []string `json:"builder"`
 	Toolchain    []string `json:"toolchain"`
 
-	//Not Parsing these out atm
+	//Only parsed for TemplateVariables
 	ProjectOptions   []interface{} `json:"projectOptions"`
 	MakeVariables    map[string]interface{}
 	IndexerVariables map[string]interface{}
 }
+
+// TemplateVariable is a value chosen when a sample is created. Every
+// occurrence of Match in the sample's file contents and names is replaced
+// with the chosen value, which defaults to Default.
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Match       string `json:"match"`
+	Default     string `json:"default"`
+}
+
+// TemplateVariables returns the variables the sample declares. Entries of
+// projectOptions that are objects with a name declare a variable, as do the
+// entries of MakeVariables, which map a name to the text it replaces or to
+// an object like the projectOptions ones. When no match is given the
+// default is what gets replaced.
+func (f Fields) TemplateVariables() []TemplateVariable {
+	var vars []TemplateVariable
+	seen := make(map[string]bool)
+	add := func(v TemplateVariable) {
+		if v.Match == "" {
+			v.Match = v.Default
+		}
+		if v.Default == "" {
+			v.Default = v.Match
+		}
+		if v.Name == "" || v.Match == "" || seen[v.Name] {
+			return
+		}
+		seen[v.Name] = true
+		vars = append(vars, v)
+	}
+
+	for _, o := range f.ProjectOptions {
+		if m, ok := o.(map[string]interface{}); ok {
+			add(variableFromMap("", m))
+		}
+	}
+
+	var names []string
+	for name := range f.MakeVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch v := f.MakeVariables[name].(type) {
+		case string:
+			add(TemplateVariable{Name: name, Match: v})
+		case map[string]interface{}:
+			add(variableFromMap(name, v))
+		}
+	}
+	return vars
+}
+
+func variableFromMap(name string, m map[string]interface{}) TemplateVariable {
+	str := func(key string) string {
+		s, _ := m[key].(string)
+		return s
+	}
+	v := TemplateVariable{Name: str("name"), Description: str("description"), Match: str("match"), Default: str("default")}
+	if v.Name == "" {
+		v.Name = name
+	}
+	return v
+}
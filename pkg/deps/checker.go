@@ -28,19 +28,14 @@ You may continue and view the sample without the prerequisites. To install the m
 	compilerReg = "compiler\\|(.*)"
 )
 
-// CheckDeps as
+// CheckDeps checks the dependencies of a sample at the oneAPI root, returning
+// a message describing what is missing and a non-zero error code when
+// anything is. See Check for the outcome of each dependency.
 func CheckDeps(dependencies []string, root string) (msg string, errCode int) {
-	//dependencies are both "normal" component dependencies ( ["mkl", "vtune"])
-	//and "special" dependencies  ( ["pkg|mraa", "compiler|icc"])
-	componentDeps, specialDeps := separatethSheepsGoats(dependencies)
-
-	componentMsg, componentErrCode := checkComponentDeps(componentDeps, root)
-	specialMsg, specialErrCode := checkSpecialDeps(specialDeps, root)
-
-	//now gather results and return
-	msg, errCode = simplifyMsgErrCode(specialMsg, specialErrCode, componentMsg, componentErrCode)
-	return msg, errCode
+	r := Check(dependencies, root)
+	return r.Message(), r.ErrCode()
 }
+
 func simplifyMsgErrCode(msg1 string, errCode1 int, msg2 string, errCode2 int) (msg string, errCode int) {
 	//takes a two pairs of messages and error codes and returns their concatenation (or whatever is appropriate)
 	msg = ""
@@ -59,64 +54,85 @@ func simplifyMsgErrCode(msg1 string, errCode1 int, msg2 string, errCode2 int) (m
 	return msg, errCode
 }
 
-func checkComponentDeps(dependencies []string, root string) (msg string, errCode int) {
-
-	var missing []string
+func componentDependencies(dependencies []string, root string) []Dependency {
+	var found []Dependency
 	for _, k := range dependencies {
-		if _, err := os.Stat(filepath.Join(root, k)); os.IsNotExist(err) {
+		name, c, err := parseConstraint(k)
+		d := Dependency{Spec: k, Name: name, Kind: KindComponent, Status: StatusPresent, URL: remediationURL(name)}
+		if c != nil {
+			d.Required = c.String()
+		}
+		installed := scanComponent(root, name)
+		dir, latest := installed.Path, installed.Latest
+		switch _, statErr := os.Stat(dir); {
+		case err != nil:
+			d.Status = StatusUnverifiable
+			d.Detail = err.Error()
+		case os.IsNotExist(statErr):
 			//does NOT EXIST
-			missing = append(missing, k)
+			d.Status = StatusMissing
+		case c == nil:
+			d.Path = dir
+			d.Version = latest
+		default:
+			//any installed version will do, not only the latest
+			v, versions := selectVersion(c, append(installed.Versions, latest))
+			d.setVersion(c, v, versions, versionDir(dir, v))
 		}
+		found = append(found, d)
 	}
-	// Something was missing, get a message
-	if len(missing) > 0 {
-		msg := GenerateMessage(missing)
-		return msg, -1
-	}
-	return "", 0
+	return found
 }
 
-func checkPackageDeps(packageDeps []string, root string) (msg string, errCode int) {
+func packageDependencies(packageDeps []string, root string) []Dependency {
 	// deps = pckg|<package-name>|url
 	// 1. check for pkg-config
-	// 1.F   if not: message returned says "this sample requires <package-name> which we unable to verify.  Be sure it is installed. <url>."
+	// 1.F   if not: read the .pc files ourselves, see 2.
 	// 1.T   if so: call    `pkg-config --exists <package-name>`
-	// 1.T.T if exists - OK, no message
-	// 1.T.F if not: "this sample requires <package-name> which is not installed. You can obtain it here: <url>"
-
-	//errCode -1 no package    , -2 no pkg-config
-
-	msg = ""
-	errCode = 0
-	divider := ""
+	// 1.T.T if exists - OK
+	// 1.T.F if not: 2. it may still be in the oneAPI root, which pkg-config does not search
+	// 2.T   if the package and what it Requires are found - OK
+	// 2.F   if not: missing, "this sample requires <package-name> which is not installed. You can obtain it here: <url>"
 
 	//0. setup regex that will parse dependency
 	regEx := regexp.MustCompile(pkgReg)
 
 	//1.
 	_, pkgErr := exec.LookPath("pkg-config")
+	native := newPkgConfig(root)
 
+	var found []Dependency
 	for _, dep := range packageDeps {
 		pkg, url := parseDep(regEx, dep)
-		err := pkgErr
-		if err == nil {
-			cmd := exec.Command("pkg-config", "--exists", pkg)
-			err = cmd.Run()
-			if err == nil {
-				//we are good to go.
-			} else {
-				msg = msg + divider + fmt.Sprintf("this sample requires %s which is not installed. To obtain: %s", pkg, url)
-				divider = "\n"
-				errCode = -1
+		pkg, c, err := parseConstraint(pkg)
+		d := Dependency{Spec: dep, Name: pkg, Kind: KindPackage, URL: url}
+		if c != nil {
+			d.Required = c.String()
+		}
+		switch {
+		case err != nil:
+			d.Status = StatusUnverifiable
+			d.Detail = err.Error()
+		case pkgErr != nil || exec.Command("pkg-config", "--exists", pkg).Run() != nil:
+			//2.
+			d.checkPC(native, c)
+		default:
+			//we are good to go.
+			d.Status = StatusPresent
+			if out, err := exec.Command("pkg-config", "--modversion", pkg).Output(); err == nil {
+				d.Version = strings.TrimSpace(string(out))
+			}
+			if out, err := exec.Command("pkg-config", "--variable=prefix", pkg).Output(); err == nil {
+				d.Path = strings.TrimSpace(string(out))
+			}
+			if c != nil {
+				v, versions := selectVersion(c, []string{d.Version})
+				d.setVersion(c, v, versions, d.Path)
 			}
-		} else {
-			msg = msg + divider + fmt.Sprintf("this sample requires %s which we are unable to verify. Please make sure it is installed. %s", pkg, url)
-			divider = "\n"
-			errCode = -2
 		}
+		found = append(found, d)
 	}
-
-	return msg, errCode
+	return found
 }
 
 func parseDep(re *regexp.Regexp, dep string) (pkg string, url string) {
@@ -137,71 +153,53 @@ func parseDep(re *regexp.Regexp, dep string) (pkg string, url string) {
 	return pkg, url
 }
 
-func checkCompilerDeps(compilerDeps []string, root string) (msg string, errCode int) {
-
-	msg = ""
-	errCode = 0
-	var missing []string
-
-	winCompilers := map[string]string{
-		"icc":     "bin/intel64/icl.exe",
-		"fortran": "bin/intel64/ifort.exe",
-		"dpcpp":   "bin/dpcpp.exe",
-		"icpc":    "bin/intel64/icpc.exe",
-		"icx":     "bin/icx.exe",
-		"icpcx":   "bin/icpcx.exe",
-		"ifx":     "bin/ifx.exe",
-	}
-	linCompilers := map[string]string{
-		"icc":     "bin/intel64/icc",
-		"fortran": "bin/intel64/ifort",
-		"dpcpp":   "bin/dpcpp",
-		"icpc":    "bin/intel64/icpc",
-		"icx":     "bin/icx",
-		"icpcx":   "bin/icpcx",
-		"ifx":     "bin/ifx",
-	}
-	macCompilers := map[string]string{
-		"icpc":  "bin/intel64/icpc",
-		"icc":   "bin/intel64/icc",
-		"ifort": "bin/intel64/ifort",
-		"icx":   "bin/icx",
-		"icpcx": "bin/icpcx",
-		"ifx":   "bin/ifx",
-	}
-
+func compilerDependencies(compilerDeps []string, root string) []Dependency {
 	compilerRoot := GetCompilerRoot(root)
+	compilers, tableErr := loadCompilerTables()
 
 	//0. setup regex that will parse dependency
 	regEx := regexp.MustCompile(compilerReg)
 
+	var found []Dependency
 	for _, dep := range compilerDeps {
 		compiler, _ := parseDep(regEx, dep)
-		var pathTail string
-
-		switch runtime.GOOS {
-		case "linux":
-			pathTail = linCompilers[compiler]
-		case "windows":
-			pathTail = winCompilers[compiler]
-		case "darwin":
-			pathTail = macCompilers[compiler]
-		default:
-			msg = "Cannot check Compiler, unsupported OS"
-			errCode = 01
-			return msg, errCode
+		compiler, c, err := parseConstraint(compiler)
+		d := Dependency{Spec: dep, Name: compiler, Kind: KindCompiler, URL: remediationURL(compiler)}
+		if c != nil {
+			d.Required = c.String()
+		}
+		if err == nil {
+			err = tableErr
+		}
+		if err != nil {
+			d.Status = StatusUnverifiable
+			d.Detail = err.Error()
+			found = append(found, d)
+			continue
 		}
 
-		fullPath := filepath.Join(compilerRoot, pathTail)
-		if pathTail == "" || !fileExists(fullPath) {
-			missing = append(missing, compiler)
+		def, known := findCompiler(compilers, compiler)
+		if known && len(def.Paths[runtime.GOOS]) == 0 {
+			d.Status = StatusUnverifiable
+			d.Detail = fmt.Sprintf("Cannot check Compiler %s, unsupported OS", compiler)
+			found = append(found, d)
+			continue
 		}
+
+		if !known {
+			d.Status = StatusMissing
+		} else if c != nil {
+			d.checkCompilerVersion(c, root, def)
+		} else if fullPath := def.locate(runtime.GOOS, compilerRoot); fullPath == "" {
+			d.Status = StatusMissing
+		} else {
+			d.Status = StatusPresent
+			d.Path = fullPath
+			d.Version = detectVersion(compilerRoot)
+		}
+		found = append(found, d)
 	}
-	if len(missing) > 0 {
-		msg = GenerateMessage(missing)
-		errCode = 01
-	}
-	return msg, errCode
+	return found
 }
 
 func fileExists(path string) bool {
@@ -209,16 +207,6 @@ func fileExists(path string) bool {
 	return !os.IsNotExist(err)
 }
 
-func checkSpecialDeps(specialDependencies []string, root string) (msg string, errCode int) {
-	packageDeps, remainingDeps := separatethSheepsGoatsRhematosC(specialDependencies, func(dep string) bool { return strings.HasPrefix(dep, "pkg|") })
-	compilerDeps, remainingDeps := separatethSheepsGoatsRhematosC(remainingDeps, func(dep string) bool { return strings.HasPrefix(dep, "compiler|") })
-
-	packageMsg, packageErrCode := checkPackageDeps(packageDeps, root)
-	compilerMsg, compilerErrCode := checkCompilerDeps(compilerDeps, root)
-	msg, errCode = simplifyMsgErrCode(packageMsg, packageErrCode, compilerMsg, compilerErrCode)
-	return msg, errCode
-}
-
 // GetOneAPIRoot gets the root the OneAPI installation
 // based on the ONEAPI_ROOT
 func GetOneAPIRoot() (path string, err error) {
@@ -228,13 +216,7 @@ func GetOneAPIRoot() (path string, err error) {
 		return "", fmt.Errorf("%s not defined.  Be sure to run oneapi environment script ( source setvars.sh )", rootEnvKey)
 	}
 
-	tmp := filepath.Dir(root)
-	tmp = strings.ToLower(filepath.Base(tmp))
-	if tmp == "oneapi" {
-		return filepath.Dir(root), nil
-	}
-
-	return root, nil
+	return normaliseRoot(root), nil
 }
 
 // CMPLR_ROOT was, for awhile, always defined in the environment. But no longer.
@@ -277,13 +259,13 @@ func GenerateMessage(missing []string) string {
 	var slug string
 	fallbackMsg := fmt.Sprintf(formatStr, strings.Join(missing, " "), baseURL, slug)
 
-	//1  read in compmapping.json
-	var mapping []compDir
-	//err := readSomeJSON(filepath.Join("json", "compmapping.json"), &mapping)
-	err := parseSomeJSON(compmappingJSON, &mapping)
+	//1  read in compmapping.json, the freshest copy served by the aggregator
+	//or else the one built in. So are the others.
+	m, err := loadMappings()
 	if err != nil {
 		return fallbackMsg
 	}
+	mapping := m.components
 	//1.1  translate missing to id list
 	idList := mapStringArr(missing, func(miss string) string {
 		for _, v := range mapping {
@@ -295,12 +277,7 @@ func GenerateMessage(missing []string) string {
 	})
 
 	//2.0 read in suite-components.json
-	var sweetComps []suiteComponent
-	//err = readSomeJSON(filepath.Join("json", "suite-components.json"), &sweetComps)
-	err = parseSomeJSON(sweetComponentsJSON, &sweetComps)
-	if err != nil {
-		return fallbackMsg
-	}
+	sweetComps := m.suiteComponents
 	//2.1 expand id-list to toolkits
 
 	var matchedSuite []string
@@ -313,12 +290,7 @@ func GenerateMessage(missing []string) string {
 
 	if len(matchedSuite) > 1 {
 		//4.0 read suites.json
-		var suites []suite
-		//err = readSomeJSON(filepath.Join("json", "suites.json"), &suites)
-		err = parseSomeJSON(suitesJSON, &suites)
-		if err != nil {
-			return fallbackMsg
-		}
+		suites := m.suites
 
 		var composed string
 
@@ -342,6 +314,7 @@ func readSomeJSON(path string, something interface{}) error {
 	if err != nil {
 		return err
 	}
+	defer jsonFile.Close()
 	byteValue, err := ioutil.ReadAll(jsonFile)
 	if err != nil {
 		return err
@@ -77,8 +77,8 @@ func TestCheckCompilerDeps(t *testing.T) {
 
 	root := setupTestRoot(t, testingGold)
 	deps := []string{"compiler|gomer"}
-	_, errCode := checkCompilerDeps(deps, root)
-	if errCode == 0 {
+	found := compilerDependencies(deps, root)
+	if len(found) != 1 || found[0].Status == StatusPresent {
 		t.Errorf("gomer compiler should never have been found")
 	}
 }
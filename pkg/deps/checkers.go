@@ -0,0 +1,202 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Kinds of dependency checked by the checkers built into the CLI, besides
+// packages and compilers
+const (
+	KindCommand = "cmd"    // a command on the PATH, i.e. "cmd|cmake>=3.20"
+	KindEnv     = "env"    // an environment variable, i.e. "env|ZE_AFFINITY_MASK"
+	KindPython  = "python" // a Python module, i.e. "python|numpy"
+	KindLibrary = "lib"    // a shared library, i.e. "lib|libOpenCL.so"
+	KindFile    = "file"   // a file, absolute or relative to the oneAPI root
+)
+
+func init() {
+	mustRegister(KindPackage, CheckerFunc(func(r Request) Dependency {
+		return packageDependencies([]string{r.Spec}, r.Root)[0]
+	}))
+	mustRegister(KindCompiler, CheckerFunc(func(r Request) Dependency {
+		return compilerDependencies([]string{r.Spec}, r.Root)[0]
+	}))
+	mustRegister(KindCommand, CheckerFunc(checkCommand))
+	mustRegister(KindEnv, CheckerFunc(checkEnv))
+	mustRegister(KindPython, CheckerFunc(checkPython))
+	mustRegister(KindLibrary, CheckerFunc(checkLibrary))
+	mustRegister(KindFile, CheckerFunc(checkFile))
+}
+
+func checkCommand(r Request) Dependency {
+	d := Dependency{Name: r.Name}
+	p, err := exec.LookPath(r.Name)
+	if err != nil {
+		d.Status = StatusMissing
+		d.Detail = fmt.Sprintf("%s is not on the PATH", r.Name)
+		return d
+	}
+	d.Status = StatusPresent
+	d.Path = p
+	if r.Constraint != nil {
+		d.Version = commandVersion(p)
+	}
+	return d
+}
+
+func checkEnv(r Request) Dependency {
+	d := Dependency{Name: r.Name}
+	v, ok := os.LookupEnv(r.Name)
+	if !ok {
+		d.Status = StatusMissing
+		d.Detail = fmt.Sprintf("%s is not set", r.Name)
+		return d
+	}
+	d.Status = StatusPresent
+	if r.Constraint != nil {
+		d.Version = v // the value is only shown when it is restricted
+	}
+	return d
+}
+
+// pythonProbe prints the version and file of the module named by its argument
+const pythonProbe = `import importlib, sys
+m = importlib.import_module(sys.argv[1])
+print(getattr(m, "__version__", ""))
+print(getattr(m, "__file__", "") or "")`
+
+func checkPython(r Request) Dependency {
+	d := Dependency{Name: r.Name}
+	var python string
+	for _, p := range []string{"python3", "python"} {
+		if found, err := exec.LookPath(p); err == nil {
+			python = found
+			break
+		}
+	}
+	if python == "" {
+		d.Status = StatusUnverifiable
+		d.Detail = "python is not installed"
+		return d
+	}
+	out, err := exec.Command(python, "-c", pythonProbe, r.Name).Output()
+	if err != nil {
+		d.Status = StatusMissing
+		d.Detail = fmt.Sprintf("the Python module %s is not installed", r.Name)
+		return d
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	d.Status = StatusPresent
+	d.Version = strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		d.Path = strings.TrimSpace(lines[1])
+	}
+	return d
+}
+
+var libVersionReg = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// libraryDirs are searched for shared libraries, the directories of the
+// library path then those of the components of the oneAPI root and the system
+func libraryDirs(root string) []string {
+	var dirs []string
+	switch runtime.GOOS {
+	case "windows":
+		dirs = filepath.SplitList(os.Getenv("PATH"))
+	case "darwin":
+		dirs = filepath.SplitList(os.Getenv("DYLD_LIBRARY_PATH"))
+	default:
+		dirs = filepath.SplitList(os.Getenv("LD_LIBRARY_PATH"))
+	}
+	if root != "" {
+		for _, pattern := range []string{"lib", "lib/intel64", "lib/x64", "linux/lib", "bin"} {
+			matches, _ := filepath.Glob(filepath.Join(root, "*", "latest", filepath.FromSlash(pattern)))
+			dirs = append(dirs, matches...)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		dirs = append(dirs, "/usr/local/lib", "/usr/lib64", "/usr/lib", "/lib64", "/lib")
+		multiarch, _ := filepath.Glob("/usr/lib/*-linux-gnu*")
+		dirs = append(dirs, multiarch...)
+	}
+	return dirs
+}
+
+// checkLibrary finds a shared library by its name or a versioned name of it,
+// i.e. libOpenCL.so.1.0.0 for libOpenCL.so, the version is told by the name
+func checkLibrary(r Request) Dependency {
+	d := Dependency{Name: r.Name}
+	for _, dir := range libraryDirs(r.Root) {
+		if dir == "" {
+			continue
+		}
+		if p := filepath.Join(dir, r.Name); fileExists(p) {
+			d.Status = StatusPresent
+			d.Path = p
+			d.Version = libraryVersion(r.Name, p)
+			return d
+		}
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			if strings.HasPrefix(info.Name(), r.Name+".") {
+				p := filepath.Join(dir, info.Name())
+				d.Status = StatusPresent
+				d.Path = p
+				d.Version = libraryVersion(r.Name, p)
+				return d
+			}
+		}
+	}
+	d.Status = StatusMissing
+	d.Detail = fmt.Sprintf("%s was not found in the library path", r.Name)
+	return d
+}
+
+// libraryVersion tells the version of a library from the versioned name it
+// is, or links to
+func libraryVersion(name string, p string) string {
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		p = real
+	}
+	base := filepath.Base(p)
+	if !strings.HasPrefix(base, name+".") {
+		return ""
+	}
+	if v := strings.TrimPrefix(base, name+"."); libVersionReg.MatchString(v) {
+		return v
+	}
+	return ""
+}
+
+func checkFile(r Request) Dependency {
+	d := Dependency{Name: r.Name}
+	p := os.ExpandEnv(filepath.FromSlash(r.Name))
+	if !filepath.IsAbs(p) {
+		if r.Root == "" {
+			d.Status = StatusUnverifiable
+			d.Detail = fmt.Sprintf("%s is relative to the oneAPI root, which was not found", r.Name)
+			return d
+		}
+		p = filepath.Join(r.Root, p)
+	}
+	if !fileExists(p) {
+		d.Status = StatusMissing
+		d.Detail = fmt.Sprintf("%s does not exist", p)
+		return d
+	}
+	d.Status = StatusPresent
+	d.Path = p
+	return d
+}
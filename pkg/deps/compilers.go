@@ -0,0 +1,232 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CompilerDef describes how to find a compiler of the oneAPI root, as a
+// "compiler|<name>" dependency names it
+type CompilerDef struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Component is the component of the oneAPI root it comes with, as
+	// dependencies name it, i.e. "dpcpp"
+	Component string `json:"component,omitempty"`
+	// Paths lists per OS (as runtime.GOOS) where the compiler may be,
+	// relative to a version directory of the compiler. The first that
+	// exists is the compiler.
+	Paths   map[string][]string `json:"paths"`
+	Version *VersionProbe       `json:"version,omitempty"`
+}
+
+// VersionProbe tells how to ask a compiler for its version, by default it is
+// run with --version and the first dotted version in the output is taken
+type VersionProbe struct {
+	Args    []string `json:"args,omitempty"`
+	Pattern string   `json:"pattern,omitempty"` // a regexp, the first group is the version if it has one
+}
+
+// CompilerTables are the compiler tables read over the built in one, in
+// order. Tables that do not exist are skipped.
+type CompilerTables struct {
+	Served []string // served by the sample aggregator
+	User   []string // of the user, read last
+}
+
+var compilerTables struct {
+	sync.Mutex
+	CompilerTables
+}
+
+// UseCompilerTables sets the compiler tables read over the built in one
+func UseCompilerTables(tables CompilerTables) {
+	compilerTables.Lock()
+	defer compilerTables.Unlock()
+	compilerTables.CompilerTables = tables
+}
+
+// LoadCompilers reads the built in compiler table then the served tables
+// and those of the user, a compiler of a later table replaces the one with
+// the same name. A served table ValidateCompilerTable rejects is skipped,
+// only tables of the user may tell how to probe the version of a compiler.
+func LoadCompilers(tables CompilerTables) ([]CompilerDef, error) {
+	var defs []CompilerDef
+	if err := parseSomeJSON(compilersJSON, &defs); err != nil {
+		return nil, err
+	}
+	for _, p := range tables.Served {
+		if !fileExists(p) {
+			continue
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil || ValidateCompilerTable(b) != nil {
+			continue
+		}
+		var table []CompilerDef
+		if err := json.Unmarshal(b, &table); err != nil {
+			continue
+		}
+		for _, def := range table {
+			defs = replaceCompiler(defs, def)
+		}
+	}
+	for _, p := range tables.User {
+		if !fileExists(p) {
+			continue
+		}
+		var table []CompilerDef
+		if err := readSomeJSON(p, &table); err != nil {
+			return nil, fmt.Errorf("invalid compiler table %s - %v", p, err)
+		}
+		for _, def := range table {
+			if err := def.validate(true); err != nil {
+				return nil, fmt.Errorf("invalid compiler table %s - %v", p, err)
+			}
+			defs = replaceCompiler(defs, def)
+		}
+	}
+	return defs, nil
+}
+
+// ValidateCompilerTable checks a compiler table served by the sample
+// aggregator: every compiler has a name, its paths stay in the version
+// directory of the compiler and it does not tell how to probe its version
+func ValidateCompilerTable(b []byte) error {
+	var table []CompilerDef
+	if err := json.Unmarshal(b, &table); err != nil {
+		return err
+	}
+	for _, def := range table {
+		if err := def.validate(false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks a compiler of a table, only one of the user may have a
+// version probe
+func (def CompilerDef) validate(user bool) error {
+	if def.Name == "" {
+		return fmt.Errorf("a compiler has no name")
+	}
+	for goos, paths := range def.Paths {
+		for _, p := range paths {
+			if !isInsidePath(p) {
+				return fmt.Errorf("path %q of %s on %s is not inside the directory of the compiler", p, def.Name, goos)
+			}
+		}
+	}
+	if def.Version == nil {
+		return nil
+	}
+	if !user {
+		return fmt.Errorf("%s has a version probe, only a table of the user may", def.Name)
+	}
+	if def.Version.Pattern != "" {
+		if _, err := regexp.Compile(def.Version.Pattern); err != nil {
+			return fmt.Errorf("version pattern of %s: %v", def.Name, err)
+		}
+	}
+	return nil
+}
+
+// isInsidePath tells if a path of a compiler is relative and has no ".."
+// segments, as either OS separates them
+func isInsidePath(p string) bool {
+	if p == "" || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return false
+	}
+	p = strings.Replace(p, "\\", "/", -1)
+	if strings.HasPrefix(p, "/") || (len(p) > 1 && p[1] == ':') {
+		return false
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func replaceCompiler(defs []CompilerDef, def CompilerDef) []CompilerDef {
+	for i := range defs {
+		if defs[i].Name == def.Name {
+			defs[i] = def
+			return defs
+		}
+	}
+	return append(defs, def)
+}
+
+// loadCompilerTables loads the compiler tables set with UseCompilerTables
+func loadCompilerTables() ([]CompilerDef, error) {
+	compilerTables.Lock()
+	tables := compilerTables.CompilerTables
+	compilerTables.Unlock()
+	return LoadCompilers(tables)
+}
+
+// findCompiler finds a compiler by name or alias
+func findCompiler(defs []CompilerDef, name string) (CompilerDef, bool) {
+	for _, def := range defs {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	for _, def := range defs {
+		if contains(def.Aliases, name) {
+			return def, true
+		}
+	}
+	return CompilerDef{}, false
+}
+
+// locate finds the compiler in a version directory of the compiler, it is
+// empty when it is not there
+func (def CompilerDef) locate(goos string, dir string) string {
+	for _, p := range def.Paths[goos] {
+		if !isInsidePath(p) {
+			continue
+		}
+		if full := filepath.Join(dir, filepath.FromSlash(p)); fileExists(full) {
+			return full
+		}
+	}
+	return ""
+}
+
+// probeVersion asks the compiler at bin for its version
+func (def CompilerDef) probeVersion(bin string) string {
+	if def.Version == nil {
+		return commandVersion(bin)
+	}
+	args := def.Version.Args
+	if len(args) == 0 {
+		args = []string{"--version"}
+	}
+	out, err := exec.Command(bin, args...).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	if def.Version.Pattern == "" {
+		return versionReg.FindString(string(out))
+	}
+	match := regexp.MustCompile(def.Version.Pattern).FindStringSubmatch(string(out))
+	switch len(match) {
+	case 0:
+		return ""
+	case 1:
+		return match[0]
+	}
+	return match[1]
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLoadCompilers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneapi-compilers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defs, err := LoadCompilers(CompilerTables{User: []string{filepath.Join(dir, "missing.json")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def, ok := findCompiler(defs, "fortran"); !ok || def.Name != "ifort" {
+		t.Errorf("fortran should be an alias of ifort, found %v", def.Name)
+	}
+
+	served := filepath.Join(dir, "served.json")
+	user := filepath.Join(dir, "user.json")
+	ioutil.WriteFile(served, []byte(`[{"name":"icx","paths":{"linux":["bin/compiler/icx"]}},{"name":"icpx-new","aliases":["icpxn"],"paths":{"linux":["bin/icpxn"]}}]`), 0644)
+	ioutil.WriteFile(user, []byte(`[{"name":"icx","paths":{"linux":["mine/icx"]}}]`), 0644)
+	defs, err = LoadCompilers(CompilerTables{Served: []string{served}, User: []string{user}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def, _ := findCompiler(defs, "icx"); len(def.Paths["linux"]) != 1 || def.Paths["linux"][0] != "mine/icx" {
+		t.Errorf("icx of the user table should win, found %v", def.Paths)
+	}
+	if def, ok := findCompiler(defs, "icpxn"); !ok || def.Name != "icpx-new" {
+		t.Errorf("compiler added by a table not found by its alias")
+	}
+	if def, ok := findCompiler(defs, "ifx"); !ok || len(def.Paths["linux"]) == 0 {
+		t.Errorf("built in compilers should be kept")
+	}
+
+	for _, bad := range []string{
+		`{"name":"icx"}`,
+		`[{"paths":{}}]`,
+		`[{"name":"icx","version":{"pattern":"("}}]`,
+		`[{"name":"icx","paths":{"linux":["/usr/bin/icx"]}}]`,
+		`[{"name":"icx","paths":{"windows":["C:\\bin\\icx.exe"]}}]`,
+		`[{"name":"icx","paths":{"linux":["bin/../../../tmp/icx"]}}]`,
+		`[{"name":"icx","paths":{"windows":["bin\\..\\..\\icx.exe"]}}]`,
+	} {
+		ioutil.WriteFile(user, []byte(bad), 0644)
+		if _, err := LoadCompilers(CompilerTables{User: []string{user}}); err == nil {
+			t.Errorf("invalid table %s loaded", bad)
+		}
+		if ValidateCompilerTable([]byte(bad)) == nil {
+			t.Errorf("invalid table %s is valid to serve", bad)
+		}
+	}
+
+	//A served table may not probe versions, one that is not valid is skipped
+	probing := `[{"name":"icx","paths":{"linux":["bin/icx"]},"version":{"args":["-c","touch /tmp/x"]}}]`
+	if ValidateCompilerTable([]byte(probing)) == nil {
+		t.Errorf("a served table should not probe versions")
+	}
+	ioutil.WriteFile(user, []byte(probing), 0644)
+	if _, err := LoadCompilers(CompilerTables{User: []string{user}}); err != nil {
+		t.Errorf("the user table may probe versions - %v", err)
+	}
+	for _, bad := range []string{probing, `{"name":"icx"}`, `[{"name":"icx","paths":{"linux":["/usr/bin/icx"]}}]`} {
+		ioutil.WriteFile(served, []byte(bad), 0644)
+		defs, err := LoadCompilers(CompilerTables{Served: []string{served}})
+		if err != nil {
+			t.Errorf("an invalid served table should be skipped - %v", err)
+		}
+		if def, _ := findCompiler(defs, "icx"); def.Version != nil || def.Paths["linux"][0] == "/usr/bin/icx" {
+			t.Errorf("icx of the invalid served table %s was loaded", bad)
+		}
+	}
+}
+
+func TestCompilerTables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script standing in for the compiler")
+	}
+	root, err := ioutil.TempDir("", "oneapi-compilers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	defer UseCompilerTables(CompilerTables{})
+
+	bin := filepath.Join(root, "compiler", "latest", "newlayout", "icz")
+	os.MkdirAll(filepath.Dir(bin), 0755)
+	ioutil.WriteFile(bin, []byte("#!/bin/sh\necho 'icz version: 7.1 (build 2024.1.0)'\n"), 0755)
+
+	table := filepath.Join(root, "compilers.json")
+	ioutil.WriteFile(table, []byte(`[{"name":"icz","paths":{"`+runtime.GOOS+`":["bin/icz","newlayout/icz"]},"version":{"pattern":"build ([0-9.]+)"}}]`), 0644)
+
+	if found := compilerDependencies([]string{"compiler|icz"}, root); found[0].Status != StatusMissing {
+		t.Errorf("icz is not a built in compiler, got %s", found[0].Status)
+	}
+
+	UseCompilerTables(CompilerTables{User: []string{table}})
+	found := compilerDependencies([]string{"compiler|icz", "compiler|icz>=2024.1"}, root)
+	if found[0].Status != StatusPresent || found[0].Path != bin {
+		t.Errorf("icz should be found at %s, got %s at %s", bin, found[0].Status, found[0].Path)
+	}
+	if found[1].Status != StatusPresent || found[1].Version != "2024.1.0" {
+		t.Errorf("icz should be present as 2024.1.0, got %s %s - %s", found[1].Status, found[1].Version, found[1].Detail)
+	}
+
+	ioutil.WriteFile(table, []byte(`not json`), 0644)
+	if found := compilerDependencies([]string{"compiler|icz"}, root); found[0].Status != StatusUnverifiable {
+		t.Errorf("an invalid table should leave compilers unverifiable, got %s", found[0].Status)
+	}
+}
@@ -58,6 +58,9 @@ const compmappingJSON = `
 // https://jsonlint.com/
 // they have lots of small problems (missing quotes, stray commas, missing commas) that
 // Node.js forgives.
+//
+// compmappingJSON, sweetComponentsJSON and suitesJSON are only used when the sample
+// aggregator has not served fresher copies, see UseMappings.
 const sweetComponentsJSON = `
 [
     
@@ -125,3 +128,66 @@ const suitesJSON = `
     { "id": "VTuneProfiler", "label": "Intel® VTune™ Profiler", "urlSlug": "vtune-profiler", "baseToolkit": "recommended" }
 ]
 `
+
+// compilersJSON is the built in compiler table, see CompilerDef. Paths are
+// relative to a version directory of the compiler, newer layouts first, the
+// component is the directory of compmappingJSON that installs the compiler.
+const compilersJSON = `
+[
+    { "name": "icx", "component": "dpcpp", "paths": {
+        "linux": ["bin/icx", "linux/bin/icx"],
+        "windows": ["bin/icx.exe", "windows/bin/icx.exe"],
+        "darwin": ["bin/icx", "mac/bin/icx"] } },
+    { "name": "icpx", "component": "dpcpp", "paths": {
+        "linux": ["bin/icpx", "linux/bin/icpx"],
+        "windows": ["bin/icpx.exe", "windows/bin/icpx.exe"],
+        "darwin": ["bin/icpx", "mac/bin/icpx"] } },
+    { "name": "icpcx", "component": "dpcpp", "paths": {
+        "linux": ["bin/icpcx", "linux/bin/icpcx"],
+        "windows": ["bin/icpcx.exe", "windows/bin/icpcx.exe"],
+        "darwin": ["bin/icpcx", "mac/bin/icpcx"] } },
+    { "name": "dpcpp", "component": "dpcpp", "paths": {
+        "linux": ["bin/dpcpp", "linux/bin/dpcpp"],
+        "windows": ["bin/dpcpp.exe", "windows/bin/dpcpp.exe"] } },
+    { "name": "ifx", "component": "fortran", "paths": {
+        "linux": ["bin/ifx", "linux/bin/ifx"],
+        "windows": ["bin/ifx.exe", "windows/bin/ifx.exe"],
+        "darwin": ["bin/ifx", "mac/bin/ifx"] } },
+    { "name": "icc", "component": "icc", "paths": {
+        "linux": ["bin/intel64/icc", "linux/bin/intel64/icc"],
+        "windows": ["bin/intel64/icl.exe", "windows/bin/intel64/icl.exe"],
+        "darwin": ["bin/intel64/icc", "mac/bin/intel64/icc"] } },
+    { "name": "icpc", "component": "icc", "paths": {
+        "linux": ["bin/intel64/icpc", "linux/bin/intel64/icpc"],
+        "windows": ["bin/intel64/icpc.exe", "windows/bin/intel64/icpc.exe"],
+        "darwin": ["bin/intel64/icpc", "mac/bin/intel64/icpc"] } },
+    { "name": "ifort", "component": "fortran", "aliases": ["fortran"], "paths": {
+        "linux": ["bin/intel64/ifort", "linux/bin/intel64/ifort"],
+        "windows": ["bin/intel64/ifort.exe", "windows/bin/intel64/ifort.exe"],
+        "darwin": ["bin/intel64/ifort", "mac/bin/intel64/ifort"] } }
+]
+`
+
+// distroPackagesJSON names the distribution packages of pkg-config packages,
+// where they are not lib<name>-dev for apt and pkgconfig(<name>) for dnf and
+// zypper, see Report.Plan.
+const distroPackagesJSON = `
+[
+    { "pkg": "mraa", "apt": "libmraa-dev", "dnf": "mraa-devel", "zypper": "mraa-devel" },
+    { "pkg": "upm", "apt": "libupm-dev", "dnf": "upm-devel", "zypper": "upm-devel" },
+    { "pkg": "OpenCL", "apt": "ocl-icd-opencl-dev" },
+    { "pkg": "libusb-1.0", "apt": "libusb-1.0-0-dev" },
+    { "pkg": "glib-2.0", "apt": "libglib2.0-dev" },
+    { "pkg": "gstreamer-1.0", "apt": "libgstreamer1.0-dev" },
+    { "pkg": "sdl2", "apt": "libsdl2-dev" },
+    { "pkg": "zlib", "apt": "zlib1g-dev" },
+    { "pkg": "openssl", "apt": "libssl-dev" },
+    { "pkg": "libcurl", "apt": "libcurl4-openssl-dev" },
+    { "pkg": "x11", "apt": "libx11-dev" },
+    { "pkg": "gl", "apt": "libgl-dev" },
+    { "pkg": "glfw3", "apt": "libglfw3-dev" },
+    { "pkg": "opencv4", "apt": "libopencv-dev" },
+    { "pkg": "hwloc", "apt": "libhwloc-dev" },
+    { "pkg": "level-zero", "apt": "libze-dev" }
+]
+`
@@ -0,0 +1,165 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Installation is a oneAPI installation found on the machine
+type Installation struct {
+	Root   string `json:"root"`
+	Source string `json:"source"` // how it was found, i.e. "ONEAPI_ROOT" or "which icx"
+}
+
+// componentRootVars are set by setvars for single components, each points
+// inside the directory of its component
+var componentRootVars = []string{"CMPLR_ROOT", "MKLROOT", "TBBROOT", "DAALROOT", "IPPROOT", "DNNLROOT", "CCL_ROOT", "I_MPI_ROOT", "DPL_ROOT", "ADVISOR_DIR", "VTUNE_PROFILER_DIR"}
+
+// compilerCommands are looked up on the PATH
+var compilerCommands = []string{"icx", "icpx", "ifx", "dpcpp"}
+
+// discovery is what DiscoverRoots looks at, tests replace it
+type discovery struct {
+	getenv   func(string) string
+	lookPath func(string) (string, error)
+	standard []string
+}
+
+func defaultDiscovery() discovery {
+	d := discovery{getenv: os.Getenv, lookPath: exec.LookPath}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("ProgramFiles(x86)"); dir != "" {
+			d.standard = append(d.standard, filepath.Join(dir, "Intel", "oneAPI"))
+		}
+		if dir := os.Getenv("ProgramFiles"); dir != "" {
+			d.standard = append(d.standard, filepath.Join(dir, "Intel", "oneAPI"))
+		}
+	} else {
+		d.standard = append(d.standard, "/opt/intel/oneapi")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		d.standard = append(d.standard, filepath.Join(home, "intel", "oneapi"))
+	}
+	return d
+}
+
+// DiscoverRoots finds the oneAPI installations of the machine, most specific
+// first: ONEAPI_ROOT, the installations the component variables of setvars
+// (i.e. CMPLR_ROOT, MKLROOT) point into, those of the compilers on the PATH
+// and the standard install locations. Each installation is listed once.
+func DiscoverRoots() []Installation {
+	return defaultDiscovery().roots()
+}
+
+func (d discovery) roots() []Installation {
+	var found []Installation
+	seen := make(map[string]bool)
+	add := func(root string, source string) {
+		if root == "" {
+			return
+		}
+		key := root
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			key = real
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		found = append(found, Installation{Root: root, Source: source})
+	}
+
+	if env := d.getenv(rootEnvKey); env != "" {
+		add(normaliseRoot(env), rootEnvKey)
+	}
+	for _, v := range componentRootVars {
+		if dir := d.getenv(v); dir != "" {
+			add(rootAbove(dir), v)
+		}
+	}
+	for _, c := range compilerCommands {
+		if bin, err := d.lookPath(c); err == nil {
+			if real, err := filepath.EvalSymlinks(bin); err == nil {
+				bin = real
+			}
+			add(rootAbove(filepath.Dir(bin)), "which "+c)
+		}
+	}
+	for _, dir := range d.standard {
+		if looksLikeRoot(dir) {
+			add(dir, "standard location")
+		}
+	}
+	return found
+}
+
+// FindOneAPIRoot is the root of ONEAPI_ROOT or, when it is not set, of the
+// first installation DiscoverRoots finds
+func FindOneAPIRoot() (string, error) {
+	installs := DiscoverRoots()
+	if len(installs) == 0 {
+		return "", fmt.Errorf("no oneAPI installation found and %s not defined.  Be sure to run oneapi environment script ( source setvars.sh )", rootEnvKey)
+	}
+	return installs[0].Root, nil
+}
+
+// normaliseRoot copes with ONEAPI_ROOT pointing into a versioned directory
+// of the root, as GetOneAPIRoot does
+func normaliseRoot(root string) string {
+	if strings.ToLower(filepath.Base(filepath.Dir(root))) == "oneapi" {
+		return filepath.Dir(root)
+	}
+	return root
+}
+
+// rootAbove finds the installation a directory of a component is in, i.e.
+// <root>/compiler/2024.0/bin, by walking up to the first directory that
+// looks like a oneAPI root
+func rootAbove(dir string) string {
+	var fallback string
+	for i := 0; i < 5; i++ {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		if hasSetvars(dir) {
+			return dir
+		}
+		if fallback == "" && looksLikeRoot(dir) {
+			fallback = dir
+		}
+	}
+	return fallback
+}
+
+func hasSetvars(dir string) bool {
+	return fileExists(filepath.Join(dir, "setvars.sh")) || fileExists(filepath.Join(dir, "setvars.bat"))
+}
+
+// looksLikeRoot is true for a directory with setvars or the directory of a
+// known component
+func looksLikeRoot(dir string) bool {
+	if dir == "" || !fileExists(dir) {
+		return false
+	}
+	if hasSetvars(dir) || fileExists(filepath.Join(dir, "compiler", "latest")) {
+		return true
+	}
+	m, err := loadMappings()
+	if err != nil {
+		return false
+	}
+	for _, cd := range m.components {
+		if fileExists(filepath.Join(dir, cd.Dir, "latest")) {
+			return true
+		}
+	}
+	return false
+}
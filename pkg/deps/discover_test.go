@@ -0,0 +1,103 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeRoot lays out a oneAPI root with setvars and a versioned compiler
+func fakeRoot(t *testing.T, root string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, "compiler", "2024.0", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "setvars.sh"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneapi-discover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	envRoot := filepath.Join(dir, "env")
+	mklRoot := filepath.Join(dir, "mkl")
+	pathRoot := filepath.Join(dir, "path")
+	optRoot := filepath.Join(dir, "opt")
+	for _, r := range []string{envRoot, mklRoot, pathRoot, optRoot} {
+		fakeRoot(t, r)
+	}
+	if err := os.MkdirAll(filepath.Join(mklRoot, "mkl", "2024.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"ONEAPI_ROOT": envRoot,
+		"MKLROOT":     filepath.Join(mklRoot, "mkl", "2024.0"),
+		"CMPLR_ROOT":  filepath.Join(envRoot, "compiler", "2024.0"),
+	}
+	d := discovery{
+		getenv: func(k string) string { return env[k] },
+		lookPath: func(c string) (string, error) {
+			if c == "icx" {
+				return filepath.Join(pathRoot, "compiler", "2024.0", "bin", "icx"), nil
+			}
+			return "", fmt.Errorf("%s not found", c)
+		},
+		standard: []string{optRoot, envRoot, filepath.Join(dir, "missing")},
+	}
+
+	expected := []Installation{
+		{Root: envRoot, Source: "ONEAPI_ROOT"},
+		{Root: mklRoot, Source: "MKLROOT"},
+		{Root: pathRoot, Source: "which icx"},
+		{Root: optRoot, Source: "standard location"},
+	}
+	if got := d.roots(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("roots() = %v, expected %v", got, expected)
+	}
+
+	d = discovery{getenv: func(string) string { return "" }, lookPath: d.lookPath}
+	if got := d.roots(); len(got) != 1 || got[0].Root != pathRoot {
+		t.Errorf("roots() without the environment = %v, expected only %s", got, pathRoot)
+	}
+}
+
+func TestRootAbove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneapi-discover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "oneapi")
+	fakeRoot(t, root)
+	if got := rootAbove(filepath.Join(root, "compiler", "2024.0", "bin")); got != root {
+		t.Errorf("rootAbove(<root>/compiler/2024.0/bin) = %s, expected %s", got, root)
+	}
+
+	//Without setvars, a root is told by the latest directory of a component
+	bare := filepath.Join(dir, "bare")
+	if err := os.MkdirAll(filepath.Join(bare, "compiler", "latest", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if !looksLikeRoot(bare) {
+		t.Errorf("looksLikeRoot(%s) = false, expected true", bare)
+	}
+	if got := rootAbove(filepath.Join(bare, "compiler", "latest", "bin")); got != bare {
+		t.Errorf("rootAbove(<bare>/compiler/latest/bin) = %s, expected %s", got, bare)
+	}
+
+	if got := rootAbove(filepath.Join(dir, "nothing", "bin")); got != "" {
+		t.Errorf("rootAbove outside a root = %s, expected none", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// Component is a component installed in a oneAPI root
+type Component struct {
+	Name        string   `json:"name"` // the directory of the component, as dependencies name it
+	ComponentID string   `json:"componentId,omitempty"`
+	Suite       string   `json:"suite,omitempty"` // the toolkit it comes with, the primary one when there are several
+	SuiteID     string   `json:"suiteId,omitempty"`
+	Path        string   `json:"path"`
+	Versions    []string `json:"versions"`         // newest first
+	Latest      string   `json:"latest,omitempty"` // the version "latest" points to
+}
+
+// Components lists the components installed in the oneAPI root, sorted by
+// name. A component is a directory with a "latest" directory or versioned
+// directories, or one known to the component mapping.
+func Components(root string) ([]Component, error) {
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	m, err := loadMappings()
+	if err != nil {
+		return nil, err
+	}
+
+	components := []Component{}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		c := scanComponent(root, info.Name())
+		for _, cd := range m.components {
+			if cd.Dir == c.Name {
+				c.ComponentID = cd.ComponentId
+			}
+		}
+		if c.ComponentID == "" && c.Latest == "" && len(c.Versions) == 0 && !fileExists(filepath.Join(c.Path, "latest")) {
+			continue
+		}
+		c.SuiteID = primarySuite(m.suiteComponents, c.ComponentID)
+		for _, s := range m.suites {
+			if s.SuiteId == c.SuiteID {
+				c.Suite = s.Label
+			}
+		}
+		components = append(components, c)
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].Name < components[j].Name
+	})
+	return components, nil
+}
+
+// scanComponent finds the versions of the component in directory name of root
+func scanComponent(root string, name string) Component {
+	dir := filepath.Join(root, name)
+	c := Component{Name: name, Path: dir, Versions: installedVersions(dir), Latest: detectVersion(filepath.Join(dir, "latest"))}
+	if c.Versions == nil {
+		c.Versions = []string{}
+	}
+	return c
+}
+
+// primarySuite is the suite a component comes with, the primary one when
+// it comes with several
+func primarySuite(sweetComps []suiteComponent, componentID string) string {
+	var suiteID string
+	for _, sc := range sweetComps {
+		if componentID != "" && sc.ComponentId == componentID && (suiteID == "" || sc.Primary) {
+			suiteID = sc.SuiteId
+			if sc.Primary {
+				break
+			}
+		}
+	}
+	return suiteID
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComponents(t *testing.T) {
+	root, err := ioutil.TempDir("", "oneapi-components")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, dir := range []string{"mkl/2023.2", "mkl/2024.0", "compiler/2024.0", "etc/conf", "mystery/1.0", "vtune"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("2023.2", filepath.Join(root, "mkl", "latest")); err != nil {
+		t.Skip("symlinks are not supported", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "setvars.sh"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := Components(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range components {
+		names = append(names, c.Name)
+	}
+	if expected := []string{"compiler", "mkl", "mystery", "vtune"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Components() = %v, expected %v", names, expected)
+	}
+
+	mkl := components[1]
+	if !reflect.DeepEqual(mkl.Versions, []string{"2024.0", "2023.2"}) || mkl.Latest != "2023.2" {
+		t.Errorf("mkl has versions %v and latest %s, expected [2024.0 2023.2] and 2023.2", mkl.Versions, mkl.Latest)
+	}
+	if mkl.ComponentID != "intel_math_kernel_library" || mkl.SuiteID != "oneAPIKit" || mkl.Suite == "" {
+		t.Errorf("mkl maps to %s of %s (%s), expected intel_math_kernel_library of oneAPIKit", mkl.ComponentID, mkl.SuiteID, mkl.Suite)
+	}
+	if mystery := components[2]; mystery.ComponentID != "" || mystery.Suite != "" {
+		t.Errorf("unknown component maps to %s of %s", mystery.ComponentID, mystery.Suite)
+	}
+	if vtune := components[3]; len(vtune.Versions) != 0 || vtune.Latest != "" {
+		t.Errorf("vtune without versions has versions %v and latest %s", vtune.Versions, vtune.Latest)
+	}
+
+	if _, err := Components(filepath.Join(root, "missing")); err == nil {
+		t.Errorf("Components() of a missing root succeeded")
+	}
+}
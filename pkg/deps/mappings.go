@@ -0,0 +1,90 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MappingSources are copies of the mappings of components to toolkits, as
+// served by the sample aggregator. Each lists the paths a copy may be at.
+type MappingSources struct {
+	Components      []string // of compmappingJSON
+	SuiteComponents []string // of sweetComponentsJSON
+	Suites          []string // of suitesJSON
+	DistroPackages  []string // of distroPackagesJSON
+}
+
+var mappingSources struct {
+	sync.Mutex
+	MappingSources
+}
+
+// UseMappings sets the copies of the mappings to use. Of each mapping the
+// freshest copy that can be read is used, the one built into the CLI when
+// there is none.
+func UseMappings(sources MappingSources) {
+	mappingSources.Lock()
+	defer mappingSources.Unlock()
+	mappingSources.MappingSources = sources
+}
+
+// mappings of components to toolkits
+type mappings struct {
+	components      []compDir
+	suiteComponents []suiteComponent
+	suites          []suite
+	distroPackages  []distroPackage
+}
+
+// loadMappings loads the mappings set with UseMappings
+func loadMappings() (mappings, error) {
+	mappingSources.Lock()
+	sources := mappingSources.MappingSources
+	mappingSources.Unlock()
+
+	var m mappings
+	if err := loadMapping(sources.Components, compmappingJSON, &m.components); err != nil {
+		return m, err
+	}
+	if err := loadMapping(sources.SuiteComponents, sweetComponentsJSON, &m.suiteComponents); err != nil {
+		return m, err
+	}
+	if err := loadMapping(sources.Suites, suitesJSON, &m.suites); err != nil {
+		return m, err
+	}
+	return m, loadMapping(sources.DistroPackages, distroPackagesJSON, &m.distroPackages)
+}
+
+// loadMapping reads the most recently modified copy at paths that parses,
+// or else the embedded mapping
+func loadMapping(paths []string, embedded string, something interface{}) error {
+	type candidate struct {
+		path     string
+		modified time.Time
+	}
+	var copies []candidate
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			copies = append(copies, candidate{p, info.ModTime()})
+		}
+	}
+	sort.SliceStable(copies, func(i, j int) bool {
+		return copies[i].modified.After(copies[j].modified)
+	})
+	for _, c := range copies {
+		//an empty mapping is as good as none
+		var entries []json.RawMessage
+		if readSomeJSON(c.path, &entries) != nil || len(entries) == 0 {
+			continue
+		}
+		if readSomeJSON(c.path, something) == nil {
+			return nil
+		}
+	}
+	return parseSomeJSON(embedded, something)
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUseMappings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneapi-mappings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer UseMappings(MappingSources{})
+
+	builtIn := remediationURL("mkl")
+	if builtIn != baseURL+"oneapi-kit" {
+		t.Fatalf("mkl should come with the base toolkit, got %s", builtIn)
+	}
+
+	system := filepath.Join(dir, "system")
+	user := filepath.Join(dir, "user")
+	os.MkdirAll(system, 0755)
+	os.MkdirAll(user, 0755)
+	write := func(d string, name string, content string, modified time.Time) string {
+		p := filepath.Join(d, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, modified, modified)
+		return p
+	}
+	old := time.Now().Add(-time.Hour)
+	sources := MappingSources{
+		Components: []string{
+			write(system, "compmapping.json", `[{"componentId":"gizmo_id", "dir":"gizmo"}]`, old),
+			write(user, "compmapping.json", `[{"componentId":"gizmo_id", "dir":"gizmo"}, {"componentId":"widget_id", "dir":"widget"}]`, time.Now()),
+		},
+		SuiteComponents: []string{
+			write(user, "suite-components.json", `[{"suiteId":"GizmoKit", "componentId":"gizmo_id", "primary":true}, {"suiteId":"WidgetKit", "componentId":"widget_id", "primary":true}]`, old),
+			write(system, "suite-components.json", `not json`, time.Now()),
+		},
+		Suites: []string{
+			write(user, "suites.json", `[{"id":"GizmoKit", "urlSlug":"gizmo-kit"}, {"id":"WidgetKit", "urlSlug":"widget-kit"}]`, time.Now()),
+		},
+	}
+	UseMappings(sources)
+
+	if url := remediationURL("widget"); url != baseURL+"widget-kit" {
+		t.Errorf("the freshest mapping should be used, got %s", url)
+	}
+	msg := GenerateMessage([]string{"gizmo", "widget"})
+	if !strings.Contains(msg, "gizmo-kit") || !strings.Contains(msg, "widget-kit") {
+		t.Errorf("the served mappings should point to both toolkits, got %s", msg)
+	}
+
+	//Empty copies are no better than none
+	write(user, "compmapping.json", `[]`, time.Now())
+	if url := remediationURL("widget"); url != baseURL {
+		t.Errorf("widget is only in the empty copy, got %s", url)
+	}
+	if url := remediationURL("gizmo"); url != baseURL+"gizmo-kit" {
+		t.Errorf("the older copy should be used, got %s", url)
+	}
+
+	UseMappings(MappingSources{Components: []string{filepath.Join(dir, "missing.json")}})
+	if url := remediationURL("mkl"); url != builtIn {
+		t.Errorf("the built in mappings should be used, got %s", url)
+	}
+}
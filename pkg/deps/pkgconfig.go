@@ -0,0 +1,243 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// pcFile is a pkg-config .pc file
+type pcFile struct {
+	Name      string // the package, as the file is named
+	Path      string
+	Version   string
+	Requires  []pcRequirement // Requires and Requires.private, both must be installed
+	variables map[string]string
+}
+
+// pcRequirement is a package listed in Requires, i.e. "glib-2.0 >= 2.50"
+type pcRequirement struct {
+	Name       string
+	Constraint *Constraint
+}
+
+var pcVariableReg = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+var pcOperatorReg = regexp.MustCompile(`==|!=|<=|>=|=|<|>`)
+
+// parsePC reads a .pc file, expanding the variables of its fields
+func parsePC(path string) (*pcFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pc := &pcFile{
+		Name:      strings.TrimSuffix(filepath.Base(path), ".pc"),
+		Path:      path,
+		variables: map[string]string{"pcfiledir": filepath.Dir(path)},
+	}
+	var line string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		//a trailing \ continues a line
+		l := scanner.Text()
+		if strings.HasSuffix(l, "\\") {
+			line += strings.TrimSuffix(l, "\\")
+			continue
+		}
+		l = line + l
+		line = ""
+		if i := strings.Index(l, "#"); i >= 0 {
+			l = l[:i]
+		}
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+
+		i := strings.IndexAny(l, ":=")
+		if i <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(l[:i])
+		value := pc.expand(strings.TrimSpace(l[i+1:]))
+		if l[i] == '=' {
+			pc.variables[key] = value
+			continue
+		}
+		switch key {
+		case "Version":
+			pc.Version = value
+		case "Requires", "Requires.private":
+			reqs, err := parseRequires(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s in %s - %v", key, path, err)
+			}
+			pc.Requires = append(pc.Requires, reqs...)
+		}
+	}
+	return pc, scanner.Err()
+}
+
+func (pc *pcFile) expand(value string) string {
+	return pcVariableReg.ReplaceAllStringFunc(value, func(v string) string {
+		return pc.variables[v[2:len(v)-1]]
+	})
+}
+
+// variable is the value of a variable of the .pc file, i.e. "prefix"
+func (pc *pcFile) variable(name string) string {
+	return pc.variables[name]
+}
+
+// parseRequires parses the packages of a Requires field, separated by
+// commas or spaces and each optionally followed by an operator and version,
+// with or without spaces around the operator
+func parseRequires(value string) ([]pcRequirement, error) {
+	value = pcOperatorReg.ReplaceAllString(strings.ReplaceAll(value, ",", " "), " $0 ")
+	fields := strings.Fields(value)
+	var reqs []pcRequirement
+	for i := 0; i < len(fields); i++ {
+		req := pcRequirement{Name: fields[i]}
+		if i+1 < len(fields) && isPCOperator(fields[i+1]) {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("no version after %s %s", fields[i], fields[i+1])
+			}
+			op := fields[i+1]
+			if op == "=" {
+				op = "=="
+			}
+			req.Constraint = &Constraint{Op: op, Version: fields[i+2]}
+			i += 2
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func isPCOperator(s string) bool {
+	switch s {
+	case "=", "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// pkgConfigDirs are searched for .pc files: PKG_CONFIG_PATH, the
+// directories of the oneAPI root and the default directories of the system,
+// which PKG_CONFIG_LIBDIR replaces
+func pkgConfigDirs(root string) []string {
+	dirs := filepath.SplitList(os.Getenv("PKG_CONFIG_PATH"))
+	if root != "" {
+		for _, pattern := range []string{"lib/pkgconfig", "*/latest/lib/pkgconfig", "*/latest/lib/intel64/pkgconfig", "*/latest/linux/lib/pkgconfig"} {
+			matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+			dirs = append(dirs, matches...)
+		}
+	}
+	if libdir, ok := os.LookupEnv("PKG_CONFIG_LIBDIR"); ok {
+		return append(dirs, filepath.SplitList(libdir)...)
+	}
+	if runtime.GOOS == "windows" {
+		return dirs
+	}
+	dirs = append(dirs, "/usr/local/lib/pkgconfig", "/usr/local/share/pkgconfig", "/usr/lib64/pkgconfig", "/usr/lib/pkgconfig", "/usr/share/pkgconfig")
+	multiarch, _ := filepath.Glob("/usr/lib/*-linux-gnu*/pkgconfig")
+	return append(dirs, multiarch...)
+}
+
+// pkgConfig resolves packages from .pc files, as pkg-config --exists does
+type pkgConfig struct {
+	dirs  []string
+	found map[string]*pcFile
+}
+
+func newPkgConfig(root string) *pkgConfig {
+	return &pkgConfig{dirs: pkgConfigDirs(root), found: make(map[string]*pcFile)}
+}
+
+// find finds the .pc file of a package in the first directory holding one
+func (p *pkgConfig) find(name string) (*pcFile, error) {
+	if pc, ok := p.found[name]; ok {
+		return pc, nil
+	}
+	for _, dir := range p.dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name+".pc")
+		if !fileExists(path) {
+			continue
+		}
+		pc, err := parsePC(path)
+		if err != nil {
+			return nil, err
+		}
+		p.found[name] = pc
+		return pc, nil
+	}
+	return nil, nil
+}
+
+// resolve finds a package and every package it requires. The .pc file is
+// nil when the package is missing, the error explains a package it requires
+// that is missing or of a version that is not allowed. The version of the
+// package itself is left to the caller to check against c.
+func (p *pkgConfig) resolve(name string, c *Constraint) (*pcFile, error) {
+	return p.resolveChain(name, c, nil)
+}
+
+func (p *pkgConfig) resolveChain(name string, c *Constraint, chain []string) (*pcFile, error) {
+	if contains(chain, name) {
+		return nil, fmt.Errorf("%s requires itself (%s)", name, strings.Join(append(chain, name), " -> "))
+	}
+	pc, err := p.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if pc == nil {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("%s requires %s which is not installed", chain[len(chain)-1], name)
+		}
+		return nil, nil
+	}
+	if c != nil && !c.Allows(pc.Version) {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("%s requires %s%s but %s is installed", chain[len(chain)-1], name, c, pc.Version)
+		}
+		return pc, nil
+	}
+	for _, req := range pc.Requires {
+		if _, err := p.resolveChain(req.Name, req.Constraint, append(chain, name)); err != nil {
+			return nil, err
+		}
+	}
+	return pc, nil
+}
+
+// checkPC checks a package from its .pc file
+func (d *Dependency) checkPC(p *pkgConfig, c *Constraint) {
+	pc, err := p.resolve(d.Name, c)
+	switch {
+	case err != nil:
+		d.Status = StatusMissing
+		d.Detail = err.Error()
+	case pc == nil:
+		d.Status = StatusMissing
+	default:
+		d.Status = StatusPresent
+		d.Version = pc.Version
+		d.Path = pc.variable("prefix")
+		if c != nil {
+			v, versions := selectVersion(c, []string{d.Version})
+			d.setVersion(c, v, versions, d.Path)
+		}
+	}
+}
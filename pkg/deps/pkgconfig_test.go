@@ -0,0 +1,126 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePC(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name+".pc")
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestParsePC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneapi-pc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := writePC(t, dir, "gizmo", `# a comment
+prefix=/opt/gizmo
+libdir=${prefix}/lib # trailing comment
+here=${pcfiledir}
+
+Name: Gizmo
+Description: gizmos for all
+Version: 1.2.3
+Requires: widget >= 2.0, sprocket \
+  cog = 1.1 glib-2.0>=2.50,gobject-2.0 !=2.60
+Requires.private: zlib>= 1.2
+Libs: -L${libdir} -lgizmo
+`)
+	pc, err := parsePC(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Name != "gizmo" || pc.Version != "1.2.3" {
+		t.Errorf("expected gizmo 1.2.3, got %s %s", pc.Name, pc.Version)
+	}
+	if pc.variable("libdir") != "/opt/gizmo/lib" || pc.variable("here") != dir {
+		t.Errorf("variables not expanded, libdir %s here %s", pc.variable("libdir"), pc.variable("here"))
+	}
+	want := []string{"widget>=2.0", "sprocket", "cog==1.1", "glib-2.0>=2.50", "gobject-2.0!=2.60", "zlib>=1.2"}
+	if len(pc.Requires) != len(want) {
+		t.Fatalf("expected requires %v, got %+v", want, pc.Requires)
+	}
+	for i, w := range want {
+		r := pc.Requires[i]
+		got := r.Name
+		if r.Constraint != nil {
+			got += r.Constraint.String()
+		}
+		if got != w {
+			t.Errorf("expected %s, got %s", w, got)
+		}
+	}
+
+	writePC(t, dir, "bad", "Requires: widget >=\n")
+	if _, err := parsePC(filepath.Join(dir, "bad.pc")); err == nil {
+		t.Errorf("Requires without a version should not parse")
+	}
+}
+
+func TestNativePkgConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "oneapi-pc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	system := filepath.Join(root, "system")
+	oneAPI := filepath.Join(root, "oneapi")
+	writePC(t, filepath.Join(oneAPI, "tbb", "latest", "lib", "pkgconfig"), "tbb", "prefix=/opt/tbb\nVersion: 2021.11\nRequires: hwloc\n")
+	writePC(t, system, "hwloc", "Version: 2.9.0\n")
+	writePC(t, system, "gizmo", "Version: 1.2\nRequires: widget >= 2\n")
+	writePC(t, system, "widget", "Version: 1.8\n")
+	writePC(t, system, "cog", "Version: 1.0\nRequires: sprocket\n")
+	writePC(t, system, "glib-2.0", "Version: 2.72.4\n")
+	writePC(t, system, "gadget", "Version: 1.0\nRequires: glib-2.0>=2.50\nRequires.private: widget<1.5\n")
+	writePC(t, system, "loop-a", "Version: 1.0\nRequires: loop-b\n")
+	writePC(t, system, "loop-b", "Version: 1.0\nRequires: loop-a\n")
+	t.Setenv("PATH", "") // no pkg-config
+	t.Setenv("PKG_CONFIG_PATH", "")
+	t.Setenv("PKG_CONFIG_LIBDIR", system)
+
+	r := Check([]string{"pkg|tbb>=2021.10", "pkg|tbb<2021", "pkg|gizmo", "pkg|cog", "pkg|loop-a", "pkg|widget", "pkg|absent|http://absent", "pkg|gadget"}, oneAPI)
+	want := []struct{ status, detail string }{
+		{StatusPresent, ""},
+		{StatusMissing, "2021.11 is installed"},
+		{StatusMissing, "gizmo requires widget>=2 but 1.8 is installed"},
+		{StatusMissing, "cog requires sprocket which is not installed"},
+		{StatusMissing, "requires itself"},
+		{StatusPresent, ""},
+		{StatusMissing, ""},
+		{StatusMissing, "gadget requires widget<1.5 but 1.8 is installed"},
+	}
+	for i, w := range want {
+		d := r.Dependencies[i]
+		if d.Status != w.status || !strings.Contains(d.Detail, w.detail) {
+			t.Errorf("%s: expected %s (%s), got %+v", d.Spec, w.status, w.detail, d)
+		}
+	}
+	if tbb := r.Dependencies[0]; tbb.Version != "2021.11" || tbb.Path != "/opt/tbb" {
+		t.Errorf("expected tbb 2021.11 in /opt/tbb, got %+v", tbb)
+	}
+
+	//PKG_CONFIG_PATH is searched first
+	first := filepath.Join(root, "first")
+	writePC(t, first, "widget", "Version: 2.5\n")
+	t.Setenv("PKG_CONFIG_PATH", first)
+	if d := Check([]string{"pkg|gizmo"}, oneAPI).Dependencies[0]; d.Status != StatusPresent {
+		t.Errorf("expected gizmo with the widget of PKG_CONFIG_PATH, got %+v", d)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Request is a dependency of a sample of a kind with a Checker,
+// "<kind>|<name>[constraint][|<args>...]"
+type Request struct {
+	Spec       string      // as listed by the sample
+	Kind       string      // i.e. "cmd"
+	Name       string      // without the version constraint
+	Args       []string    // the parts after the name, the first is the URL to obtain it, i.e. of "pkg|mraa|url"
+	Constraint *Constraint // nil when the version is not restricted
+	Root       string      // the oneAPI root, may be ""
+}
+
+// Checker checks the dependencies of one kind. It reports the status of the
+// dependency, where it was found and its version when it can be told.
+//
+// Dependencies with a constraint are checked against the version a present
+// dependency reports. A checker selecting among several versions itself
+// sets Required.
+type Checker interface {
+	Check(r Request) Dependency
+}
+
+// CheckerFunc is a function used as a Checker
+type CheckerFunc func(r Request) Dependency
+
+// Check calls f(r)
+func (f CheckerFunc) Check(r Request) Dependency {
+	return f(r)
+}
+
+var kindReg = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+var checkers = struct {
+	sync.RWMutex
+	byKind map[string]Checker
+}{byKind: make(map[string]Checker)}
+
+// RegisterChecker adds a Checker for the dependencies of a kind, i.e.
+// "mytool" checks "mytool|<name>". Kinds are lower case and registered once.
+func RegisterChecker(kind string, c Checker) error {
+	if !kindReg.MatchString(kind) || kind == KindComponent {
+		return fmt.Errorf("'%s' is not a valid kind of dependency", kind)
+	}
+	if c == nil {
+		return fmt.Errorf("no checker passed for kind '%s'", kind)
+	}
+	checkers.Lock()
+	defer checkers.Unlock()
+	if _, ok := checkers.byKind[kind]; ok {
+		return fmt.Errorf("a checker for kind '%s' is already registered", kind)
+	}
+	checkers.byKind[kind] = c
+	return nil
+}
+
+// Kinds lists the kinds of dependency with a Checker
+func Kinds() []string {
+	checkers.RLock()
+	defer checkers.RUnlock()
+	var kinds []string
+	for k := range checkers.byKind {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func mustRegister(kind string, c Checker) {
+	if err := RegisterChecker(kind, c); err != nil {
+		panic(err)
+	}
+}
+
+// checkSpecial checks a "<kind>|..." dependency with the checker of its kind
+func checkSpecial(dep string, root string) Dependency {
+	parts := strings.Split(dep, "|")
+	name, c, err := parseConstraint(parts[1])
+	r := Request{Spec: dep, Kind: parts[0], Name: name, Args: parts[2:], Constraint: c, Root: root}
+
+	checkers.RLock()
+	checker, ok := checkers.byKind[r.Kind]
+	checkers.RUnlock()
+	if !ok {
+		return Dependency{Spec: dep, Name: parts[1], Kind: r.Kind, Status: StatusUnverifiable, Detail: fmt.Sprintf("unknown kind of dependency '%s'", r.Kind)}
+	}
+
+	d := Dependency{Spec: dep, Name: name, Kind: r.Kind}
+	if err != nil {
+		d.Status = StatusUnverifiable
+		d.Detail = err.Error()
+		return d
+	}
+	d = checker.Check(r)
+	if d.Spec == "" {
+		d.Spec = dep
+	}
+	if d.Kind == "" {
+		d.Kind = r.Kind
+	}
+	if d.Name == "" {
+		d.Name = name
+	}
+	if d.URL == "" && len(r.Args) > 0 {
+		d.URL = r.Args[0]
+	}
+	if c != nil && d.Required == "" {
+		d.Required = c.String()
+		if d.Status == StatusPresent {
+			v, versions := selectVersion(c, []string{d.Version})
+			d.setVersion(c, v, versions, d.Path)
+		}
+	}
+	return d
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRegisterChecker(t *testing.T) {
+	var got Request
+	err := RegisterChecker("gadget", CheckerFunc(func(r Request) Dependency {
+		got = r
+		if r.Name == "sprocket" {
+			return Dependency{Status: StatusPresent, Version: "1.4"}
+		}
+		return Dependency{Status: StatusMissing}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		checkers.Lock()
+		delete(checkers.byKind, "gadget")
+		checkers.Unlock()
+	}()
+
+	for _, kind := range []string{"gadget", KindPackage, KindComponent, "Bad|Kind", ""} {
+		if err := RegisterChecker(kind, CheckerFunc(checkEnv)); err == nil {
+			t.Errorf("registering kind '%s' should fail", kind)
+		}
+	}
+	if !contains(Kinds(), "gadget") || !contains(Kinds(), KindCommand) {
+		t.Errorf("expected gadget and the built in kinds, got %v", Kinds())
+	}
+
+	r := Check([]string{"gadget|sprocket>=1.2|http://gadgets", "gadget|sprocket>=2", "gadget|cog"}, "/oneapi")
+	if got.Kind != "gadget" || got.Name != "cog" || got.Root != "/oneapi" || got.Constraint != nil {
+		t.Errorf("unexpected request %+v", got)
+	}
+	want := []struct{ status, required, version string }{
+		{StatusPresent, ">=1.2", "1.4"},
+		{StatusMissing, ">=2", "1.4"},
+		{StatusMissing, "", ""},
+	}
+	for i, w := range want {
+		d := r.Dependencies[i]
+		if d.Kind != "gadget" || d.Name != "sprocket" && d.Name != "cog" || d.Status != w.status || d.Required != w.required || d.Version != w.version {
+			t.Errorf("expected %s %s %s, got %+v", w.status, w.required, w.version, d)
+		}
+	}
+	msg, code := CheckDeps([]string{"gadget|cog|http://gadgets"}, "")
+	if code != -1 || !strings.Contains(msg, "cog") || !strings.Contains(msg, "http://gadgets") {
+		t.Errorf("expected the missing cog in the message, got %d %s", code, msg)
+	}
+}
+
+func TestBuiltInCheckers(t *testing.T) {
+	root, err := ioutil.TempDir("", "oneapi-checkers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	libs := filepath.Join(root, "tbb", "latest", "lib")
+	os.MkdirAll(libs, 0755)
+	ioutil.WriteFile(filepath.Join(libs, "libgizmo.so.2.1.0"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(root, "licensing.txt"), nil, 0644)
+	t.Setenv("ONEAPI_TEST_MASK", "3")
+	t.Setenv("LD_LIBRARY_PATH", "")
+	t.Setenv("DYLD_LIBRARY_PATH", "")
+
+	deps := []string{
+		"env|ONEAPI_TEST_MASK", "env|ONEAPI_TEST_UNSET", "env|ONEAPI_TEST_MASK>=4",
+		"file|licensing.txt", "file|" + filepath.Join(root, "missing.txt"),
+		"cmd|oneapi-no-such-command|http://commands",
+	}
+	want := []string{StatusPresent, StatusMissing, StatusMissing, StatusPresent, StatusMissing, StatusMissing}
+	if runtime.GOOS != "windows" {
+		deps = append(deps, "lib|libgizmo.so>=2", "lib|libsprocket.so")
+		want = append(want, StatusPresent, StatusMissing)
+	}
+	r := Check(deps, root)
+	for i, w := range want {
+		if d := r.Dependencies[i]; d.Status != w {
+			t.Errorf("%s: expected %s, got %+v", deps[i], w, d)
+		}
+	}
+	if d := r.Dependencies[3]; d.Path != filepath.Join(root, "licensing.txt") {
+		t.Errorf("file should be relative to the root, got %s", d.Path)
+	}
+	if d := r.Dependencies[5]; d.URL != "http://commands" || d.Detail == "" {
+		t.Errorf("expected the URL and detail of the missing command, got %+v", d)
+	}
+	if runtime.GOOS != "windows" {
+		if d := r.Dependencies[6]; d.Version != "2.1.0" || d.Path != filepath.Join(libs, "libgizmo.so.2.1.0") {
+			t.Errorf("expected libgizmo 2.1.0 in the oneAPI root, got %+v", d)
+		}
+	}
+
+	if d := Check([]string{"file|licensing.txt"}, "").Dependencies[0]; d.Status != StatusUnverifiable {
+		t.Errorf("a file relative to no root can not be checked, got %+v", d)
+	}
+}
+
+func TestCommandChecker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script standing in for the command")
+	}
+	dir, err := ioutil.TempDir("", "oneapi-checkers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "gizmo"), []byte("#!/bin/sh\necho 'gizmo version 3.22.1'\n"), 0755)
+	t.Setenv("PATH", dir)
+
+	r := Check([]string{"cmd|gizmo", "cmd|gizmo>=3.20", "cmd|gizmo<3"}, "")
+	for i, w := range []string{StatusPresent, StatusPresent, StatusMissing} {
+		if d := r.Dependencies[i]; d.Status != w {
+			t.Errorf("expected %s, got %+v", w, d)
+		}
+	}
+	if d := r.Dependencies[1]; d.Version != "3.22.1" || d.Path != filepath.Join(dir, "gizmo") {
+		t.Errorf("expected gizmo 3.22.1, got %+v", d)
+	}
+	if d := r.Dependencies[2]; !strings.Contains(d.Detail, "3.22.1") {
+		t.Errorf("expected the installed version in the detail, got %s", d.Detail)
+	}
+}
+
+func TestPythonChecker(t *testing.T) {
+	if _, err := os.Stat("/usr/bin/python3"); err != nil {
+		t.Skip("needs python3")
+	}
+	r := Check([]string{"python|json", "python|oneapi_no_such_module"}, "")
+	if d := r.Dependencies[0]; d.Status != StatusPresent || d.Path == "" {
+		t.Errorf("expected the json module, got %+v", d)
+	}
+	if d := r.Dependencies[1]; d.Status != StatusMissing {
+		t.Errorf("expected a missing module, got %+v", d)
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// distroPackage names the packages of a pkg-config package in the package
+// managers of the distributions
+type distroPackage struct {
+	Pkg    string `json:"pkg"`
+	Apt    string `json:"apt,omitempty"`
+	Dnf    string `json:"dnf,omitempty"`
+	Zypper string `json:"zypper,omitempty"`
+}
+
+// Plan is how to install the missing dependencies of a report
+type Plan struct {
+	Installers []InstallerStep `json:"installers,omitempty"` // one per toolkit
+	Packages   []PackageStep   `json:"packages,omitempty"`
+	Python     []string        `json:"python,omitempty"` // pip requirements, i.e. "numpy>=1.20"
+	Manual     []ManualStep    `json:"manual,omitempty"` // what the script can not install
+}
+
+// InstallerStep installs components with the installer of a toolkit
+type InstallerStep struct {
+	Suite        string   `json:"suite"`
+	SuiteID      string   `json:"suiteId"`
+	URL          string   `json:"url"`
+	Components   []string `json:"components"`   // installer component IDs
+	Dependencies []string `json:"dependencies"` // as the sample names them
+}
+
+// PackageStep installs a pkg-config package with the package manager
+type PackageStep struct {
+	Name   string `json:"name"`
+	Apt    string `json:"apt"`
+	Dnf    string `json:"dnf"`
+	Zypper string `json:"zypper"`
+	URL    string `json:"url,omitempty"`
+}
+
+// ManualStep is a missing dependency to take care of by hand
+type ManualStep struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Empty is true when there is nothing to install
+func (p *Plan) Empty() bool {
+	return len(p.Installers) == 0 && len(p.Packages) == 0 && len(p.Python) == 0 && len(p.Manual) == 0
+}
+
+// Plan works out how to install the missing dependencies: components and
+// compilers with the installers of their toolkits, packages with the
+// package manager and Python modules with pip. Dependencies that could not
+// be checked are left out.
+func (r *Report) Plan() *Plan {
+	p := &Plan{}
+	m, err := loadMappings()
+	if err != nil {
+		m = mappings{}
+	}
+	compilers, _ := loadCompilerTables()
+
+	for _, d := range r.Dependencies {
+		if d.Status != StatusMissing {
+			continue
+		}
+		if !safeNameReg.MatchString(d.Name) || !safeRequiredReg.MatchString(d.Required) {
+			d.Detail = fmt.Sprintf("%q is not a valid name, it is left out of the script", d.Name+d.Required)
+			p.addManual(d)
+			continue
+		}
+		switch d.Kind {
+		case KindComponent:
+			p.addComponent(m, d, d.Name)
+		case KindCompiler:
+			def, ok := findCompiler(compilers, d.Name)
+			if !ok || def.Component == "" {
+				p.addManual(d)
+				continue
+			}
+			p.addComponent(m, d, def.Component)
+		case KindPackage:
+			p.Packages = append(p.Packages, distroPackages(m, d))
+		case KindPython:
+			req := d.Name
+			if d.Required != "" {
+				req += pipConstraint(d.Required)
+			}
+			p.Python = append(p.Python, req)
+		default:
+			p.addManual(d)
+		}
+	}
+	return p
+}
+
+// addComponent adds the component of the oneAPI root installing d to the
+// installer of its toolkit
+func (p *Plan) addComponent(m mappings, d Dependency, dir string) {
+	var id string
+	for _, cd := range m.components {
+		if cd.Dir == dir {
+			id = cd.ComponentId
+		}
+	}
+	suiteID := primarySuite(m.suiteComponents, id)
+	if suiteID == "" || !safeNameReg.MatchString(id) {
+		p.addManual(d)
+		return
+	}
+	for i := range p.Installers {
+		step := &p.Installers[i]
+		if step.SuiteID == suiteID {
+			if !contains(step.Components, id) {
+				step.Components = append(step.Components, id)
+			}
+			step.Dependencies = append(step.Dependencies, d.Name)
+			return
+		}
+	}
+	step := InstallerStep{SuiteID: suiteID, Suite: suiteID, URL: baseURL + findSlug(m.suites, suiteID), Components: []string{id}, Dependencies: []string{d.Name}}
+	for _, s := range m.suites {
+		if s.SuiteId == suiteID && s.Label != "" {
+			step.Suite = s.Label
+		}
+	}
+	p.Installers = append(p.Installers, step)
+}
+
+func (p *Plan) addManual(d Dependency) {
+	detail := d.Detail
+	if detail == "" {
+		detail = fmt.Sprintf("%s%s is not installed", d.Name, d.Required)
+	}
+	p.Manual = append(p.Manual, ManualStep{Name: d.Name, Kind: d.Kind, Detail: detail, URL: d.URL})
+}
+
+// distroPackages names the distribution packages of a pkg-config package
+func distroPackages(m mappings, d Dependency) PackageStep {
+	step := PackageStep{
+		Name:   d.Name,
+		Apt:    "lib" + strings.TrimPrefix(d.Name, "lib") + "-dev",
+		Dnf:    "pkgconfig(" + d.Name + ")",
+		Zypper: "pkgconfig(" + d.Name + ")",
+		URL:    d.URL,
+	}
+	for _, dp := range m.distroPackages {
+		if dp.Pkg != d.Name {
+			continue
+		}
+		if safePackageReg.MatchString(dp.Apt) {
+			step.Apt = dp.Apt
+		}
+		if safePackageReg.MatchString(dp.Dnf) {
+			step.Dnf = dp.Dnf
+		}
+		if safePackageReg.MatchString(dp.Zypper) {
+			step.Zypper = dp.Zypper
+		}
+	}
+	return step
+}
+
+// pipConstraint writes a version constraint as pip does, 2024.* patterns
+// are ==2024.*
+func pipConstraint(required string) string {
+	if strings.HasPrefix(required, "@") {
+		return "==" + strings.TrimPrefix(required, "@")
+	}
+	return required
+}
+
+// Names written into the script as arguments, they may not start with - so
+// they are not taken as options
+var (
+	safeNameReg     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+:@/-]*$`)
+	safeRequiredReg = regexp.MustCompile(`^[A-Za-z0-9.*+,<>=!~@-]*$`)
+	safePackageReg  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+:()-]*$`)
+)
+
+var envNameReg = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// installerVar names the variable holding the path of the installer of a
+// toolkit, i.e. ONEAPI_INSTALLER_HPCKIT
+func installerVar(suiteID string) string {
+	return "ONEAPI_INSTALLER_" + envNameReg.ReplaceAllString(strings.ToUpper(suiteID), "_")
+}
+
+// shQuote quotes a word for sh
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// batchQuote quotes an argument for a batch file
+func batchQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
+
+// batchEcho escapes text echoed by a batch file, inside a block too
+var batchEcho = strings.NewReplacer("%", "%%", "^", "^^", "&", "^&", "|", "^|", "<", "^<", ">", "^>", "(", "^(", ")", "^)")
+
+var controlReg = regexp.MustCompile(`[\x00-\x1f\x7f]+`)
+
+// commentText keeps text written in a comment of the script on its line
+func commentText(s string) string {
+	return controlReg.ReplaceAllString(s, " ")
+}
+
+// Script writes the plan as a script to run on goos, a batch file for
+// windows and a shell script otherwise. The installers of the toolkits are
+// not downloaded, the script asks for them in ONEAPI_INSTALLER_<toolkit>.
+func (p *Plan) Script(goos string) string {
+	if goos == "windows" {
+		return p.batchScript()
+	}
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# Installs the missing dependencies of the sample\nset -e\n\nSUDO=sudo\n[ \"$(id -u)\" = 0 ] && SUDO=\n")
+	for _, step := range p.Installers {
+		v := installerVar(step.SuiteID)
+		fmt.Fprintf(&b, "\n# %s\n", commentText(step.Suite+": "+strings.Join(step.Dependencies, ", ")))
+		fmt.Fprintf(&b, "# download the installer from %s and set %s to it\n", commentText(step.URL), v)
+		fmt.Fprintf(&b, "$SUDO sh \"${%s:?set to the installer of the toolkit}\" -a --silent --eula accept --components %s\n", v, shQuote(strings.Join(step.Components, ":")))
+	}
+	if len(p.Packages) > 0 {
+		var apt, dnf, zypper, names []string
+		for _, pkg := range p.Packages {
+			apt = append(apt, shQuote(pkg.Apt))
+			dnf = append(dnf, shQuote(pkg.Dnf))
+			zypper = append(zypper, shQuote(pkg.Zypper))
+			names = append(names, pkg.Name)
+		}
+		fmt.Fprintf(&b, "\n# packages: %s\n", commentText(strings.Join(names, ", ")))
+		fmt.Fprintf(&b, "if command -v apt-get >/dev/null 2>&1; then\n\t$SUDO apt-get install -y %s\n", strings.Join(apt, " "))
+		fmt.Fprintf(&b, "elif command -v dnf >/dev/null 2>&1; then\n\t$SUDO dnf install -y %s\n", strings.Join(dnf, " "))
+		fmt.Fprintf(&b, "elif command -v zypper >/dev/null 2>&1; then\n\t$SUDO zypper install -y %s\n", strings.Join(zypper, " "))
+		b.WriteString("else\n")
+		for _, pkg := range p.Packages {
+			line := "please install " + pkg.Name
+			if pkg.URL != "" {
+				line += ", see " + pkg.URL
+			}
+			fmt.Fprintf(&b, "\techo %s >&2\n", shQuote(line))
+		}
+		b.WriteString("fi\n")
+	}
+	if len(p.Python) > 0 {
+		var reqs []string
+		for _, req := range p.Python {
+			reqs = append(reqs, shQuote(req))
+		}
+		fmt.Fprintf(&b, "\n# Python modules\npython3 -m pip install %s\n", strings.Join(reqs, " "))
+	}
+	if len(p.Manual) > 0 {
+		b.WriteString("\n# to take care of by hand:\n")
+		for _, m := range p.Manual {
+			b.WriteString(manualLine("# ", m) + "\n")
+		}
+	}
+	return b.String()
+}
+
+func (p *Plan) batchScript() string {
+	var b strings.Builder
+	b.WriteString("@echo off\r\nrem Installs the missing dependencies of the sample\r\n")
+	for _, step := range p.Installers {
+		v := installerVar(step.SuiteID)
+		fmt.Fprintf(&b, "\r\nrem %s\r\n", commentText(step.Suite+": "+strings.Join(step.Dependencies, ", ")))
+		fmt.Fprintf(&b, "if not defined %s (\r\n\techo download the installer from %s and set %s to it\r\n\texit /b 1\r\n)\r\n", v, batchEcho.Replace(commentText(step.URL)), v)
+		fmt.Fprintf(&b, "\"%%%s%%\" -s -a --silent --eula accept --components %s || exit /b 1\r\n", v, batchQuote(strings.Join(step.Components, ":")))
+	}
+	if len(p.Python) > 0 {
+		var reqs []string
+		for _, req := range p.Python {
+			reqs = append(reqs, batchQuote(req))
+		}
+		fmt.Fprintf(&b, "\r\nrem Python modules\r\npython -m pip install %s || exit /b 1\r\n", strings.Join(reqs, " "))
+	}
+	if len(p.Packages) > 0 || len(p.Manual) > 0 {
+		b.WriteString("\r\nrem to take care of by hand:\r\n")
+		for _, pkg := range p.Packages {
+			b.WriteString(manualLine("rem ", ManualStep{Name: pkg.Name, Kind: KindPackage, Detail: pkg.Name + " is not installed", URL: pkg.URL}) + "\r\n")
+		}
+		for _, m := range p.Manual {
+			b.WriteString(manualLine("rem ", m) + "\r\n")
+		}
+	}
+	return b.String()
+}
+
+func manualLine(comment string, m ManualStep) string {
+	line := fmt.Sprintf("%s %s: %s", m.Kind, m.Name, m.Detail)
+	if m.URL != "" {
+		line += ", see " + m.URL
+	}
+	return comment + commentText(line)
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	r := &Report{Dependencies: []Dependency{
+		{Name: "mkl", Kind: KindComponent, Status: StatusMissing},
+		{Name: "ipp", Kind: KindComponent, Status: StatusPresent},
+		{Name: "ipp", Kind: KindComponent, Status: StatusMissing},
+		{Name: "icx", Kind: KindCompiler, Status: StatusMissing},
+		{Name: "mraa", Kind: KindPackage, Status: StatusMissing, URL: "https://github.com/intel-iot-devkit/mraa"},
+		{Name: "gadget", Kind: KindPackage, Status: StatusMissing},
+		{Name: "zlib", Kind: KindPackage, Status: StatusUnverifiable},
+		{Name: "numpy", Kind: KindPython, Status: StatusMissing, Required: ">=1.20"},
+		{Name: "dpctl", Kind: KindPython, Status: StatusMissing, Required: "@0.14.*"},
+		{Name: "cmake", Kind: KindCommand, Status: StatusMissing, Detail: "cmake is not on the PATH"},
+		{Name: "ZE_AFFINITY_MASK", Kind: KindEnv, Status: StatusMissing},
+	}}
+	p := r.Plan()
+
+	if len(p.Installers) != 1 {
+		t.Fatalf("expected one installer, got %+v", p.Installers)
+	}
+	step := p.Installers[0]
+	if step.SuiteID != "oneAPIKit" || step.URL == "" {
+		t.Errorf("expected the installer of oneAPIKit, got %+v", step)
+	}
+	if want := []string{"intel_math_kernel_library", "intel_integrated_performance_primitives", "dppcpp_compiler"}; !reflect.DeepEqual(step.Components, want) {
+		t.Errorf("expected components %v, got %v", want, step.Components)
+	}
+	if want := []string{"mkl", "ipp", "icx"}; !reflect.DeepEqual(step.Dependencies, want) {
+		t.Errorf("expected dependencies %v, got %v", want, step.Dependencies)
+	}
+
+	want := []PackageStep{
+		{Name: "mraa", Apt: "libmraa-dev", Dnf: "mraa-devel", Zypper: "mraa-devel", URL: "https://github.com/intel-iot-devkit/mraa"},
+		{Name: "gadget", Apt: "libgadget-dev", Dnf: "pkgconfig(gadget)", Zypper: "pkgconfig(gadget)"},
+	}
+	if !reflect.DeepEqual(p.Packages, want) {
+		t.Errorf("expected packages %+v, got %+v", want, p.Packages)
+	}
+	if want := []string{"numpy>=1.20", "dpctl==0.14.*"}; !reflect.DeepEqual(p.Python, want) {
+		t.Errorf("expected pip requirements %v, got %v", want, p.Python)
+	}
+	if len(p.Manual) != 2 || p.Manual[0].Detail != "cmake is not on the PATH" || p.Manual[1].Detail != "ZE_AFFINITY_MASK is not installed" {
+		t.Errorf("expected cmake and ZE_AFFINITY_MASK to be done by hand, got %+v", p.Manual)
+	}
+
+	script := p.Script("linux")
+	for _, s := range []string{
+		"#!/bin/sh\n",
+		`$SUDO sh "${ONEAPI_INSTALLER_ONEAPIKIT:?`,
+		"--components 'intel_math_kernel_library:intel_integrated_performance_primitives:dppcpp_compiler'\n",
+		"$SUDO apt-get install -y 'libmraa-dev' 'libgadget-dev'\n",
+		"$SUDO dnf install -y 'mraa-devel' 'pkgconfig(gadget)'\n",
+		"python3 -m pip install 'numpy>=1.20' 'dpctl==0.14.*'\n",
+		"# cmd cmake: cmake is not on the PATH\n",
+	} {
+		if !strings.Contains(script, s) {
+			t.Errorf("expected the script to contain %q, got\n%s", s, script)
+		}
+	}
+
+	batch := p.Script("windows")
+	for _, s := range []string{
+		"@echo off\r\n",
+		"if not defined ONEAPI_INSTALLER_ONEAPIKIT (\r\n",
+		`python -m pip install "numpy>=1.20" "dpctl==0.14.*" || exit /b 1` + "\r\n",
+		"rem pkg mraa: mraa is not installed, see https://github.com/intel-iot-devkit/mraa\r\n",
+	} {
+		if !strings.Contains(batch, s) {
+			t.Errorf("expected the batch file to contain %q, got\n%s", s, batch)
+		}
+	}
+}
+
+func TestPlanHostileNames(t *testing.T) {
+	r := &Report{Dependencies: []Dependency{
+		{Name: "mkl\ntouch /tmp/pwned #", Kind: KindComponent, Status: StatusMissing},
+		{Name: "zlib$(touch /tmp/pwned)", Kind: KindPackage, Status: StatusMissing},
+		{Name: "-oAPT::Update::Pre-Invoke::=touch", Kind: KindPackage, Status: StatusMissing},
+		{Name: "numpy", Kind: KindPython, Status: StatusMissing, Required: ">=1\" & del x & \""},
+		{Name: "cmake", Kind: KindCommand, Status: StatusMissing, Detail: "cmake\r\ntouch /tmp/pwned"},
+		{Name: "mkl", Kind: KindComponent, Status: StatusMissing},
+	}}
+	p := r.Plan()
+	if len(p.Packages) != 0 || len(p.Python) != 0 || len(p.Manual) != 5 {
+		t.Fatalf("expected the hostile names to be done by hand, got %+v", p)
+	}
+	if len(p.Installers) != 1 || !reflect.DeepEqual(p.Installers[0].Dependencies, []string{"mkl"}) {
+		t.Errorf("expected only mkl to be installed, got %+v", p.Installers)
+	}
+
+	for goos, comment := range map[string]string{"linux": "#", "windows": "rem "} {
+		for _, line := range strings.Split(strings.ReplaceAll(p.Script(goos), "\r\n", "\n"), "\n") {
+			if (strings.Contains(line, "pwned") || strings.Contains(line, "del x")) && !strings.HasPrefix(line, comment) {
+				t.Errorf("%s script runs %q", goos, line)
+			}
+		}
+	}
+}
+
+func TestPlanEmpty(t *testing.T) {
+	r := &Report{Dependencies: []Dependency{
+		{Name: "mkl", Kind: KindComponent, Status: StatusPresent},
+		{Name: "zlib", Kind: KindPackage, Status: StatusUnverifiable},
+	}}
+	if p := r.Plan(); !p.Empty() {
+		t.Errorf("expected nothing to install, got %+v", p)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of dependency a sample can list
+const (
+	KindComponent = "component" // a directory of the oneAPI root, i.e. "mkl"
+	KindPackage   = "pkg"       // a pkg-config package, i.e. "pkg|mraa|url"
+	KindCompiler  = "compiler"  // a compiler of the oneAPI root, i.e. "compiler|icx"
+)
+
+// Outcomes of checking a dependency
+const (
+	StatusPresent      = "present"
+	StatusMissing      = "missing"
+	StatusUnverifiable = "unverifiable" // could not be checked, see the detail
+)
+
+// Dependency is the outcome of checking a single dependency of a sample
+type Dependency struct {
+	Spec    string `json:"spec"` // as listed by the sample
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Status  string `json:"status"`
+	Path    string `json:"path,omitempty"`    // where it was found
+	Version string `json:"version,omitempty"` // detected version, when it can be told
+	// Required is the version constraint of the sample, i.e. ">=2023.1"
+	Required string `json:"required,omitempty"`
+	URL      string `json:"url,omitempty"` // where to get it
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Report is the outcome of checking every dependency of a sample, in the
+// order the sample lists them
+type Report struct {
+	Root         string       `json:"root"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Check checks the dependencies of a sample at the oneAPI root
+func Check(dependencies []string, root string) *Report {
+	//dependencies are both "normal" component dependencies ( ["mkl", "vtune"])
+	//and "special" dependencies  ( ["pkg|mraa", "compiler|icc"]) checked by
+	//the checker of their kind
+	componentDeps, _ := separatethSheepsGoats(dependencies)
+	components := make(map[string]Dependency)
+	for _, d := range componentDependencies(componentDeps, root) {
+		components[d.Spec] = d
+	}
+
+	r := &Report{Root: root, Dependencies: []Dependency{}}
+	for _, dep := range dependencies {
+		d, ok := components[dep]
+		if !ok {
+			d = checkSpecial(dep, root)
+		}
+		r.Dependencies = append(r.Dependencies, d)
+	}
+	return r
+}
+
+// OK is true when every dependency is present
+func (r *Report) OK() bool {
+	for _, d := range r.Dependencies {
+		if d.Status != StatusPresent {
+			return false
+		}
+	}
+	return true
+}
+
+// Missing lists the dependencies that are not present, including those
+// that could not be checked
+func (r *Report) Missing() []Dependency {
+	var missing []Dependency
+	for _, d := range r.Dependencies {
+		if d.Status != StatusPresent {
+			missing = append(missing, d)
+		}
+	}
+	return missing
+}
+
+func (r *Report) ofKind(kind string) []Dependency {
+	var deps []Dependency
+	for _, d := range r.Dependencies {
+		if d.Kind == kind {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// Message describes what is missing, as CheckDeps always has. It is empty
+// when nothing is.
+func (r *Report) Message() string {
+	msg, _ := r.summary()
+	return msg
+}
+
+// ErrCode is the error code CheckDeps has always returned: 0 when nothing
+// is missing, otherwise the code of the last kind with a problem, in the
+// order packages (-1 missing, -2 unverifiable), compilers (1),
+// other kinds (-1 missing, -2 unverifiable) and components (-1).
+func (r *Report) ErrCode() int {
+	_, code := r.summary()
+	return code
+}
+
+func (r *Report) summary() (string, int) {
+	packageMsg, packageErrCode := packageSummary(r.ofKind(KindPackage))
+	compilerMsg, compilerErrCode := missingSummary(r.ofKind(KindCompiler), 1)
+	specialMsg, specialErrCode := simplifyMsgErrCode(packageMsg, packageErrCode, compilerMsg, compilerErrCode)
+	otherMsg, otherErrCode := otherSummary(r.Dependencies)
+	specialMsg, specialErrCode = simplifyMsgErrCode(specialMsg, specialErrCode, otherMsg, otherErrCode)
+
+	componentMsg, componentErrCode := missingSummary(r.ofKind(KindComponent), -1)
+	return simplifyMsgErrCode(specialMsg, specialErrCode, componentMsg, componentErrCode)
+}
+
+func packageSummary(packages []Dependency) (msg string, errCode int) {
+	divider := ""
+	for _, d := range packages {
+		switch d.Status {
+		case StatusMissing:
+			if d.Detail != "" {
+				msg = msg + divider + fmt.Sprintf("this sample requires %s%s, %s. To obtain: %s", d.Name, d.Required, d.Detail, d.URL)
+			} else {
+				msg = msg + divider + fmt.Sprintf("this sample requires %s which is not installed. To obtain: %s", d.Name, d.URL)
+			}
+			errCode = -1
+		case StatusUnverifiable:
+			msg = msg + divider + fmt.Sprintf("this sample requires %s which we are unable to verify. Please make sure it is installed. %s", d.Name, d.URL)
+			errCode = -2
+		default:
+			continue
+		}
+		divider = "\n"
+	}
+	return msg, errCode
+}
+
+// otherSummary explains the dependencies of the kinds other than packages,
+// compilers and components
+func otherSummary(deps []Dependency) (msg string, errCode int) {
+	divider := ""
+	for _, d := range deps {
+		switch d.Kind {
+		case KindComponent, KindPackage, KindCompiler:
+			continue
+		}
+		var line string
+		switch d.Status {
+		case StatusMissing:
+			line = fmt.Sprintf("this sample requires %s%s", d.Name, d.Required)
+			if d.Detail != "" {
+				line += ", " + d.Detail
+			}
+			line += "."
+			errCode = -1
+		case StatusUnverifiable:
+			line = fmt.Sprintf("this sample requires %s%s which we are unable to verify", d.Name, d.Required)
+			if d.Detail != "" {
+				line += ", " + d.Detail
+			}
+			line += ". Please make sure it is available."
+			if errCode == 0 {
+				errCode = -2
+			}
+		default:
+			continue
+		}
+		if d.URL != "" {
+			line += " To obtain: " + d.URL
+		}
+		msg = msg + divider + line
+		divider = "\n"
+	}
+	return msg, errCode
+}
+
+// missingSummary explains the versions that do not do and the dependencies
+// that could not be checked, then points to the toolkits holding the
+// missing dependencies
+func missingSummary(deps []Dependency, code int) (string, int) {
+	var details []string
+	var missing []string
+	for _, d := range deps {
+		if d.Status == StatusPresent {
+			continue
+		}
+		if d.Detail != "" && !contains(details, d.Detail) {
+			details = append(details, d.Detail)
+		}
+		if d.Status == StatusMissing {
+			missing = append(missing, d.Name)
+		}
+	}
+	if len(details) == 0 && len(missing) == 0 {
+		return "", 0
+	}
+	msg := strings.Join(details, "\n")
+	if len(missing) > 0 {
+		if msg != "" {
+			msg += "\n"
+		}
+		msg += GenerateMessage(missing)
+	}
+	return msg, code
+}
+
+// remediationURL is the page of the toolkit a component comes with
+func remediationURL(name string) string {
+	m, err := loadMappings()
+	if err != nil {
+		return baseURL
+	}
+
+	var id string
+	for _, v := range m.components {
+		if v.Dir == name {
+			id = v.ComponentId
+		}
+	}
+	suiteID := primarySuite(m.suiteComponents, id)
+	if suiteID == "" {
+		return baseURL
+	}
+	return baseURL + findSlug(m.suites, suiteID)
+}
+
+// detectVersion tells the version of a "latest" directory from the
+// versioned directory it links to, as the oneAPI installers lay them out
+func detectVersion(latest string) string {
+	target, err := filepath.EvalSymlinks(latest)
+	if err != nil {
+		return ""
+	}
+	if v := filepath.Base(target); v != filepath.Base(latest) {
+		return v
+	}
+	return ""
+}
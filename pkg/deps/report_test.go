@@ -0,0 +1,73 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	root := setupTestRoot(t, []string{"mkl/2021.1.1"})
+	defer os.RemoveAll(root)
+	if err := os.Symlink("2021.1.1", filepath.Join(root, "mkl", "latest")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", "") // no pkg-config, the .pc files are read instead
+	t.Setenv("PKG_CONFIG_PATH", "")
+	t.Setenv("PKG_CONFIG_LIBDIR", root)
+
+	r := Check([]string{"pkg|mraa|http://mraa", "mkl", "ipp", "compiler|gomer", "thing|x"}, root)
+	if len(r.Dependencies) != 5 {
+		t.Fatalf("expected every dependency in the report, got %+v", r.Dependencies)
+	}
+	want := []struct{ name, kind, status string }{
+		{"mraa", KindPackage, StatusMissing},
+		{"mkl", KindComponent, StatusPresent},
+		{"ipp", KindComponent, StatusMissing},
+		{"gomer", KindCompiler, StatusMissing},
+		{"x", "thing", StatusUnverifiable},
+	}
+	for i, w := range want {
+		d := r.Dependencies[i]
+		if d.Name != w.name || d.Kind != w.kind || d.Status != w.status {
+			t.Errorf("expected %s %s %s, got %+v", w.kind, w.name, w.status, d)
+		}
+	}
+
+	mkl := r.Dependencies[1]
+	if mkl.Path != filepath.Join(root, "mkl") || mkl.Version != "2021.1.1" {
+		t.Errorf("expected mkl 2021.1.1 to be resolved, got %+v", mkl)
+	}
+	if ipp := r.Dependencies[2]; !strings.HasPrefix(ipp.URL, baseURL) || ipp.URL == baseURL {
+		t.Errorf("expected a toolkit page for ipp, got %s", ipp.URL)
+	}
+	if r.OK() || len(r.Missing()) != 4 {
+		t.Errorf("expected 4 dependencies not present, got %v", r.Missing())
+	}
+
+	//The message and code are derived as they have always been
+	msg, code := CheckDeps([]string{"pkg|mraa|http://mraa", "mkl", "ipp", "compiler|gomer"}, root)
+	if code != -1 {
+		t.Errorf("the missing component should decide the code, got %d", code)
+	}
+	for _, s := range []string{"mraa which is not installed", "(gomer)", "(ipp)"} {
+		if !strings.Contains(msg, s) {
+			t.Errorf("expected '%s' in the message:\n%s", s, msg)
+		}
+	}
+	if msg, code := CheckDeps([]string{"pkg|mraa"}, root); code != -1 || !strings.Contains(msg, "mraa") {
+		t.Errorf("expected a missing package, got %d %s", code, msg)
+	}
+	if runtime.GOOS == "linux" {
+		if _, code := CheckDeps([]string{"compiler|icx"}, root); code != 1 {
+			t.Errorf("expected a missing compiler, got %d", code)
+		}
+	}
+	if msg, code := CheckDeps([]string{"mkl"}, root); code != 0 || msg != "" {
+		t.Errorf("nothing is missing, got %d %s", code, msg)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Constraint restricts the version of a dependency. A dependency name may
+// end with one, i.e. "compiler|icx>=2023.1" or "mkl@2024.*". The operators
+// are >=, <=, >, <, == (or =) and !=, @ matches a pattern where * stands for
+// any part of the version.
+type Constraint struct {
+	Op      string
+	Version string
+}
+
+var constraintReg = regexp.MustCompile(`^([^<>=!@]+)(>=|<=|==|!=|>|<|=|@)(.*)$`)
+
+// versionReg finds a version, i.e. in the output of "icx --version"
+var versionReg = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// dirVersionReg matches the versioned directories of the oneAPI root
+var dirVersionReg = regexp.MustCompile(`^\d+(\.\d+)*([.-].*)?$`)
+
+// parseConstraint splits a dependency name from its version constraint,
+// the constraint is nil when there is none
+func parseConstraint(name string) (string, *Constraint, error) {
+	match := constraintReg.FindStringSubmatch(name)
+	if match == nil {
+		return name, nil, nil
+	}
+	c := &Constraint{Op: match[2], Version: strings.TrimSpace(match[3])}
+	if c.Op == "=" {
+		c.Op = "=="
+	}
+	n := strings.TrimSpace(match[1])
+	if c.Version == "" {
+		return n, c, fmt.Errorf("invalid version constraint '%s', no version", name)
+	}
+	if _, err := path.Match(c.Version, ""); c.Op == "@" && err != nil {
+		return n, c, fmt.Errorf("invalid version pattern '%s' - %v", c.Version, err)
+	}
+	return n, c, nil
+}
+
+func (c Constraint) String() string {
+	return c.Op + c.Version
+}
+
+// Allows reports whether version v satisfies the constraint
+func (c Constraint) Allows(v string) bool {
+	if c.Op == "@" {
+		if ok, _ := path.Match(c.Version, v); ok {
+			return true
+		}
+		//2024.* also matches 2024
+		ok, _ := path.Match(c.Version, v+".0")
+		return ok
+	}
+	cmp := compareVersions(v, c.Version)
+	switch c.Op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// compareVersions compares dotted versions part by part, numerically where
+// both parts are numbers. Missing parts count as 0, so 2023.1 == 2023.1.0.
+func compareVersions(a string, b string) int {
+	pa := strings.FieldsFunc(a, isVersionSeparator)
+	pb := strings.FieldsFunc(b, isVersionSeparator)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errx := strconv.Atoi(x)
+		ny, erry := strconv.Atoi(y)
+		switch {
+		case errx == nil && erry == nil && nx != ny:
+			if nx < ny {
+				return -1
+			}
+			return 1
+		case (errx != nil || erry != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+func isVersionSeparator(r rune) bool {
+	return r == '.' || r == '-' || r == '_'
+}
+
+// installedVersions lists the versioned directories below dir, newest first
+func installedVersions(dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, info := range infos {
+		if dirVersionReg.MatchString(info.Name()) {
+			versions = append(versions, info.Name())
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+	return versions
+}
+
+// commandVersion runs a tool with --version, i.e. a compiler, and finds the
+// version it reports
+func commandVersion(tool string) string {
+	out, err := exec.Command(tool, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return versionReg.FindString(string(out))
+}
+
+// selectVersion picks the newest of the candidate versions c allows. The
+// versions that were found are returned when none is.
+func selectVersion(c *Constraint, candidates []string) (string, []string) {
+	var found []string
+	for _, v := range candidates {
+		if v == "" {
+			continue
+		}
+		if c.Allows(v) {
+			return v, nil
+		}
+		if !contains(found, v) {
+			found = append(found, v)
+		}
+	}
+	return "", found
+}
+
+// versionDetail explains why no installed version was good enough
+func versionDetail(name string, c *Constraint, found []string) string {
+	if len(found) == 0 {
+		return fmt.Sprintf("the version of %s could not be detected, %s is required", name, c)
+	}
+	return fmt.Sprintf("%s %s is installed but %s is required", name, strings.Join(found, ", "), c)
+}
+
+// versionDir is the directory of a version below the directory of a component
+func versionDir(dir string, version string) string {
+	if p := filepath.Join(dir, version); fileExists(p) {
+		return p
+	}
+	return dir
+}
+
+// setVersion records the outcome of selecting version v, at p, for c
+func (d *Dependency) setVersion(c *Constraint, v string, versions []string, p string) {
+	switch {
+	case v != "":
+		d.Status = StatusPresent
+		d.Version = v
+		d.Path = p
+	case len(versions) == 0:
+		d.Status = StatusUnverifiable
+		d.Detail = versionDetail(d.Name, c, versions)
+	default:
+		d.Status = StatusMissing
+		d.Version = versions[0]
+		d.Detail = versionDetail(d.Name, c, versions)
+	}
+}
+
+// checkCompilerVersion looks for a compiler satisfying c in the latest and
+// every versioned directory of the compiler, asking each for its version
+func (d *Dependency) checkCompilerVersion(c *Constraint, root string, def CompilerDef) {
+	compilers := filepath.Join(root, "compiler")
+	dirs := []string{GetCompilerRoot(root)}
+	for _, v := range installedVersions(compilers) {
+		dirs = append(dirs, filepath.Join(compilers, v))
+	}
+
+	var versions []string
+	installed := false
+	for _, dir := range dirs {
+		bin := def.locate(runtime.GOOS, dir)
+		if bin == "" {
+			continue
+		}
+		installed = true
+		v := def.probeVersion(bin)
+		if v == "" {
+			v = detectVersion(dir)
+		}
+		if v == "" && dirVersionReg.MatchString(filepath.Base(dir)) {
+			v = filepath.Base(dir)
+		}
+		if selected, _ := selectVersion(c, []string{v}); selected != "" {
+			d.setVersion(c, selected, nil, bin)
+			return
+		}
+		if v != "" && !contains(versions, v) {
+			versions = append(versions, v)
+		}
+	}
+	if !installed {
+		d.Status = StatusMissing
+		return
+	}
+	d.setVersion(c, "", versions, "")
+}
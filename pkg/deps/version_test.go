@@ -0,0 +1,124 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package deps
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		dep, name, constraint string
+		invalid               bool
+	}{
+		{"mkl", "mkl", "", false},
+		{"dev-utilities", "dev-utilities", "", false},
+		{"icx>=2023.1", "icx", ">=2023.1", false},
+		{"mkl@2024.*", "mkl", "@2024.*", false},
+		{"mkl=2024.0", "mkl", "==2024.0", false},
+		{"mkl != 2024.0", "mkl", "!=2024.0", false},
+		{"icx<", "icx", "", true},
+		{"mkl@[", "mkl", "", true},
+	} {
+		name, c, err := parseConstraint(tc.dep)
+		if (err != nil) != tc.invalid {
+			t.Errorf("%s: unexpected error %v", tc.dep, err)
+			continue
+		}
+		if tc.invalid {
+			continue
+		}
+		got := ""
+		if c != nil {
+			got = c.String()
+		}
+		if name != tc.name || got != tc.constraint {
+			t.Errorf("%s: expected %s %s, got %s %s", tc.dep, tc.name, tc.constraint, name, got)
+		}
+	}
+}
+
+func TestConstraintAllows(t *testing.T) {
+	for _, tc := range []struct {
+		c       Constraint
+		version string
+		allowed bool
+	}{
+		{Constraint{">=", "2023.1"}, "2023.1.0", true},
+		{Constraint{">=", "2023.1"}, "2023.0.3", false},
+		{Constraint{">=", "2023.1"}, "2024", true},
+		{Constraint{">", "2023.1"}, "2023.1", false},
+		{Constraint{"<", "2023.10"}, "2023.9", true},
+		{Constraint{"==", "2024.0"}, "2024.0.0", true},
+		{Constraint{"!=", "2024.0"}, "2024.0.1", true},
+		{Constraint{"@", "2024.*"}, "2024.0.1", true},
+		{Constraint{"@", "2024.*"}, "2024", true},
+		{Constraint{"@", "2024.*"}, "2023.2", false},
+	} {
+		if got := tc.c.Allows(tc.version); got != tc.allowed {
+			t.Errorf("%s allows %s: expected %v", tc.c, tc.version, tc.allowed)
+		}
+	}
+}
+
+func TestComponentVersions(t *testing.T) {
+	root := setupTestRoot(t, []string{"mkl/2023.0", "mkl/2024.0.1", "ipp"})
+	defer os.RemoveAll(root)
+	os.Symlink("2023.0", filepath.Join(root, "mkl", "latest"))
+
+	r := Check([]string{"mkl@2024.*", "mkl>=2025", "ipp>=2021"}, root)
+	mkl := r.Dependencies[0]
+	if mkl.Status != StatusPresent || mkl.Version != "2024.0.1" || mkl.Path != filepath.Join(root, "mkl", "2024.0.1") {
+		t.Errorf("expected mkl 2024.0.1 to satisfy @2024.*, got %+v", mkl)
+	}
+	old := r.Dependencies[1]
+	if old.Status != StatusMissing || old.Required != ">=2025" || !strings.Contains(old.Detail, "2024.0.1, 2023.0 is installed") {
+		t.Errorf("expected the installed versions to be too old, got %+v", old)
+	}
+	if ipp := r.Dependencies[2]; ipp.Status != StatusUnverifiable {
+		t.Errorf("the version of ipp can not be told, got %+v", ipp)
+	}
+	if msg := r.Message(); !strings.Contains(msg, ">=2025 is required") {
+		t.Errorf("expected the message to explain the version, got:\n%s", msg)
+	}
+}
+
+func TestCompilerVersions(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs a shell script standing in for the compiler")
+	}
+	root, err := ioutil.TempDir("", "depscheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, v := range []string{"2022.2.0", "2023.1.0"} {
+		bin := filepath.Join(root, "compiler", v, "bin", "icx")
+		os.MkdirAll(filepath.Dir(bin), 0755)
+		script := fmt.Sprintf("#!/bin/sh\necho 'Intel(R) oneAPI DPC++/C++ Compiler %s (%s.20230320)'\n", v, v)
+		if err := ioutil.WriteFile(bin, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Symlink("2022.2.0", filepath.Join(root, "compiler", "latest"))
+
+	r := Check([]string{"compiler|icx>=2023.1", "compiler|icx>=2024", "compiler|icx"}, root)
+	if d := r.Dependencies[0]; d.Status != StatusPresent || d.Version != "2023.1.0" {
+		t.Errorf("expected icx 2023.1.0 to be found, got %+v", d)
+	}
+	if d := r.Dependencies[1]; d.Status != StatusMissing || !strings.Contains(d.Detail, "2022.2.0, 2023.1.0 is installed but >=2024 is required") {
+		t.Errorf("expected icx to be too old, got %+v", d)
+	}
+	if d := r.Dependencies[2]; d.Status != StatusPresent || d.Version != "2022.2.0" {
+		t.Errorf("expected the latest icx without a constraint, got %+v", d)
+	}
+	if r.ErrCode() != 1 {
+		t.Errorf("expected the compiler error code, got %d", r.ErrCode())
+	}
+}
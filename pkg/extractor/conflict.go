@@ -0,0 +1,62 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package extractor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy decides what happens to a file in the destination that
+// would be replaced by a file from the archive
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces the existing file
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictFail refuses to extract anything if any file would collide
+	ConflictFail
+	// ConflictSkip keeps the existing file, the archive copy is not written
+	ConflictSkip
+	// ConflictBackup renames the existing file with BackupSuffix first
+	ConflictBackup
+	// ConflictAsk asks for a decision for every colliding file
+	ConflictAsk
+)
+
+// BackupSuffix is appended to existing files moved aside by ConflictBackup
+const BackupSuffix = ".orig"
+
+var conflictPolicyNames = []string{"overwrite", "fail", "skip", "backup", "ask"}
+
+// ConflictPolicyNames lists the names accepted by ParseConflictPolicy
+func ConflictPolicyNames() []string {
+	return append([]string(nil), conflictPolicyNames...)
+}
+
+// ParseConflictPolicy turns a policy name (i.e. "skip") into a ConflictPolicy
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	for i, n := range conflictPolicyNames {
+		if strings.EqualFold(n, name) {
+			return ConflictPolicy(i), nil
+		}
+	}
+	return ConflictOverwrite, fmt.Errorf("unknown conflict policy '%s', valid policies: %s", name, strings.Join(conflictPolicyNames, ", "))
+}
+
+func (p ConflictPolicy) String() string {
+	if p < 0 || int(p) >= len(conflictPolicyNames) {
+		return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+	}
+	return conflictPolicyNames[p]
+}
+
+// ConflictError is returned when files already exist at the destination
+// and the policy does not allow them to be touched
+type ConflictError struct {
+	Files []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d file(s) already exist in the destination: %s", len(e.Files), strings.Join(e.Files, ", "))
+}
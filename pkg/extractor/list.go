@@ -0,0 +1,174 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package extractor
+
+import (
+	"archive/tar"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry is a single file or directory of an archive
+type Entry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Dir    bool   `json:"dir,omitempty"`
+	SHA512 string `json:"sha512,omitempty"`
+	Exists bool   `json:"exists,omitempty"`
+}
+
+// Listing is the contents of an archive, optionally checked against a destination
+type Listing struct {
+	Entries     []Entry `json:"entries"`
+	Files       int     `json:"files"`
+	TotalSize   int64   `json:"totalSize"`
+	Destination string  `json:"destination,omitempty"`
+}
+
+// List reads the contents of a tar.gz without extracting anything, names
+// are as they would be extracted with opts. Entries are sorted by name,
+// directories implied by file names are included.
+func List(sourcetb string, opts Options) (*Listing, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var l Listing
+	dirs := make(map[string]bool)
+
+	err := opts.walk(sourcetb, func(name string, hdr *tar.Header, size int64, r io.Reader) error {
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dirs[name] = true
+		case tar.TypeReg:
+			hasher := sha512.New()
+			if _, err := io.Copy(hasher, r); err != nil {
+				return err
+			}
+			l.Entries = append(l.Entries, Entry{Name: name, Size: size, SHA512: hex.EncodeToString(hasher.Sum(nil))})
+			l.Files++
+			l.TotalSize += size
+			for d := path.Dir(name); d != "."; d = path.Dir(d) {
+				dirs[d] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for d := range dirs {
+		l.Entries = append(l.Entries, Entry{Name: d, Dir: true})
+	}
+	sort.Slice(l.Entries, func(i, j int) bool {
+		return l.Entries[i].sortKey() < l.Entries[j].sortKey()
+	})
+	return &l, nil
+}
+
+// File is the content of a single file of an archive
+type File struct {
+	Data []byte
+	Mode os.FileMode
+}
+
+// ReadFiles reads every file of the archive, as it would be extracted with
+// opts, into memory. It is meant for sample sized archives.
+func ReadFiles(sourcetb string, opts Options) (map[string]File, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	files := make(map[string]File)
+	err := opts.walk(sourcetb, func(name string, hdr *tar.Header, size int64, r io.Reader) error {
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[name] = File{Data: b, Mode: os.FileMode(hdr.Mode).Perm()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Against returns a copy of the listing with Exists set on every file that
+// is already present in the destination
+func (l *Listing) Against(out string) *Listing {
+	checked := *l
+	checked.Destination = out
+	checked.Entries = make([]Entry, len(l.Entries))
+	for i, e := range l.Entries {
+		e.Exists = !e.Dir && fileExists(filepath.Join(out, filepath.FromSlash(e.Name)))
+		checked.Entries[i] = e
+	}
+	return &checked
+}
+
+// Existing returns the names of the files that exist in the destination
+func (l *Listing) Existing() []string {
+	var existing []string
+	for _, e := range l.Entries {
+		if e.Exists {
+			existing = append(existing, e.Name)
+		}
+	}
+	return existing
+}
+
+// String renders the listing as an indented tree with sizes, followed by a summary
+func (l *Listing) String() string {
+	var b strings.Builder
+	for _, e := range l.Entries {
+		depth := strings.Count(e.Name, "/")
+		indent := strings.Repeat("  ", depth)
+		if e.Dir {
+			fmt.Fprintf(&b, "%s%s/\n", indent, path.Base(e.Name))
+			continue
+		}
+		fmt.Fprintf(&b, "%s%s (%s)", indent, path.Base(e.Name), HumanSize(e.Size))
+		if e.Exists {
+			b.WriteString(" [exists]")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n%d file(s), %s total\n", l.Files, HumanSize(l.TotalSize))
+	if l.Destination != "" {
+		fmt.Fprintf(&b, "%d existing file(s) in %s would be touched\n", len(l.Existing()), l.Destination)
+	}
+	return b.String()
+}
+
+// sortKey keeps a directory directly ahead of its own contents
+func (e Entry) sortKey() string {
+	if e.Dir {
+		return e.Name + "/"
+	}
+	return e.Name
+}
+
+// HumanSize formats a byte count, i.e. 2048 -> "2.0 KiB"
+func HumanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package extractor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// validate checks the selection options before any reading is done
+func (opts Options) validate() error {
+	if opts.StripComponents < 0 {
+		return fmt.Errorf("strip components can not be negative (%d)", opts.StripComponents)
+	}
+	for _, p := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s' - %v", p, err)
+		}
+	}
+	return opts.validateSubstitutions()
+}
+
+// target maps an archive entry name to its name in the destination, ok is
+// false when the entry is filtered out. Patterns are matched against the
+// name in the archive, before any components are stripped.
+func (opts Options) target(name string) (string, bool) {
+	if len(opts.Include) > 0 && !matchAny(opts.Include, name) {
+		return "", false
+	}
+	if matchAny(opts.Exclude, name) {
+		return "", false
+	}
+	if opts.StripComponents > 0 {
+		parts := strings.SplitN(name, "/", opts.StripComponents+1)
+		if len(parts) <= opts.StripComponents {
+			return "", false // nothing left once stripped, i.e. the wrapping directory
+		}
+		name = parts[opts.StripComponents]
+	}
+	return name, true
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash separated name against a glob. Each segment is
+// matched with path.Match, "**" matches any number of segments and a pattern
+// matching a directory matches everything below it. A pattern without a
+// slash matches at any depth, like a .gitignore entry.
+func matchGlob(pattern string, name string) bool {
+	pattern = strings.Trim(path.Clean("/"+pattern), "/")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return true
+}
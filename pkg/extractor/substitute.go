@@ -0,0 +1,77 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Substitution replaces every occurrence of Old with New
+type Substitution struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// validateSubstitutions rejects substitutions that can not be applied
+func (opts Options) validateSubstitutions() error {
+	for _, s := range opts.Substitutions {
+		if s.Old == "" {
+			return fmt.Errorf("can not substitute an empty string with '%s'", s.New)
+		}
+		if strings.ContainsAny(s.New, "/\\") && !strings.ContainsAny(s.Old, "/\\") {
+			return fmt.Errorf("substitute for '%s' can not contain a path separator ('%s')", s.Old, s.New)
+		}
+		for _, segment := range strings.FieldsFunc(s.New, func(r rune) bool { return r == '/' || r == '\\' }) {
+			if segment == "." || segment == ".." {
+				return fmt.Errorf("substitute for '%s' can not be a relative directory ('%s')", s.Old, s.New)
+			}
+		}
+	}
+	return nil
+}
+
+// replacer applies all of the substitutions in a single pass, nil when
+// there is nothing to substitute
+func (opts Options) replacer() *strings.Replacer {
+	var pairs []string
+	for _, s := range opts.Substitutions {
+		if s.Old != s.New {
+			pairs = append(pairs, s.Old, s.New)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// substitute applies the substitutions to a name and, for text files, to
+// the content. Binary files are passed through untouched. The returned
+// size is the size of the content after substitution.
+func substitute(rep *strings.Replacer, name string, hdr *tar.Header, r io.Reader) (string, io.Reader, int64, error) {
+	name = rep.Replace(name)
+	if hdr.Typeflag != tar.TypeReg {
+		return name, r, hdr.Size, nil
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return name, nil, 0, err
+	}
+	if !isBinary(b) {
+		b = []byte(rep.Replace(string(b)))
+	}
+	return name, bytes.NewReader(b), int64(len(b)), nil
+}
+
+// isBinary reports content with a NUL byte near the start, as git does
+func isBinary(b []byte) bool {
+	if len(b) > 8000 {
+		b = b[:8000]
+	}
+	return bytes.IndexByte(b, 0) >= 0
+}
@@ -5,57 +5,111 @@ package extractor
 import (
 	"archive/tar"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
-// ExtractTarGz extracts a tar.gz to the destination
-func ExtractTarGz(sourcetb string, out string) error {
+// Options controls how an archive is extracted
+type Options struct {
+	// OnConflict is applied to every file in the archive which already
+	// exists at the destination. The zero value overwrites.
+	OnConflict ConflictPolicy
 
-	//Ensure Output exists
-	if err := os.MkdirAll(out, 0750); err != nil {
-		return err
-	}
+	// Ask is called once per colliding file when OnConflict is ConflictAsk.
+	// It must return one of the non-interactive policies, returning an
+	// error aborts the extraction.
+	Ask func(name string) (ConflictPolicy, error)
 
-	tbz, err := os.Open(sourcetb)
-	if err != nil {
-		return err
-	}
+	// Include limits extraction to entries matching any of these globs,
+	// Exclude drops entries matching any of them. Both match the name in
+	// the archive, see matchGlob for the syntax.
+	Include []string
+	Exclude []string
 
-	gzr, err := gzip.NewReader(tbz)
-	if err != nil {
-		return err
-	}
-	defer gzr.Close()
+	// StripComponents removes this many leading directories from every
+	// name, entries with nothing left are dropped
+	StripComponents int
 
-	tr := tar.NewReader(gzr)
+	// Substitutions are applied to the names of the selected entries and
+	// to the content of their text files, i.e. to rename a sample project
+	Substitutions []Substitution
+}
 
-	for {
-		hdr, err := tr.Next()
+// Result records what happened to each file of the archive, names are
+// relative to the destination and use forward slashes
+type Result struct {
+	Written  []string
+	Skipped  []string
+	BackedUp []string
+}
 
-		switch {
+// ExtractTarGz extracts a tar.gz to the destination
+func ExtractTarGz(sourcetb string, out string) error {
+	_, err := Extract(sourcetb, out, Options{})
+	return err
+}
 
-		case err == io.EOF:
-			return nil // return when no more files, good path
+// Extract extracts a tar.gz to the destination applying the passed options
+func Extract(sourcetb string, out string, opts Options) (*Result, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.OnConflict == ConflictAsk && opts.Ask == nil {
+		return nil, fmt.Errorf("interactive conflict policy requested without a prompt")
+	}
 
-		case err != nil:
-			return err
+	//Nothing should be written if we are going to fail, so check up front
+	if opts.OnConflict == ConflictFail {
+		conflicts, err := Conflicts(sourcetb, out, opts)
+		if err != nil {
+			return nil, err
 		}
+		if len(conflicts) > 0 {
+			return nil, &ConflictError{Files: conflicts}
+		}
+	}
 
+	//Ensure Output exists
+	if err := os.MkdirAll(out, 0750); err != nil {
+		return nil, err
+	}
+
+	var res Result
+	err := opts.walk(sourcetb, func(name string, hdr *tar.Header, size int64, r io.Reader) error {
 		// the target location where the dir/file should be created
-		target := filepath.Join(out, hdr.Name)
+		target := filepath.Join(out, filepath.FromSlash(name))
 
 		// check the file type, are we a directory for example
 		switch hdr.Typeflag {
 
 		case tar.TypeDir:
-			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)); err != nil {
-				return err
-			}
+			return os.MkdirAll(target, os.FileMode(hdr.Mode))
 
 		// we have a file, create it with the stored attr from the header
 		case tar.TypeReg:
+			if fileExists(target) {
+				policy, err := opts.resolve(name)
+				if err != nil {
+					return err
+				}
+				switch policy {
+				case ConflictSkip:
+					res.Skipped = append(res.Skipped, name)
+					return nil
+				case ConflictBackup:
+					if err := backup(target); err != nil {
+						return err
+					}
+					res.BackedUp = append(res.BackedUp, name)
+				case ConflictFail:
+					return &ConflictError{Files: []string{name}}
+				}
+			}
+
 			//Sometimes the file can come before its directory listing, or it never has one :S
 			if !fileExists(filepath.Dir(target)) {
 				if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
@@ -63,22 +117,142 @@ func ExtractTarGz(sourcetb string, out string) error {
 				}
 			}
 
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(hdr.Mode))
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode))
 			if err != nil {
 				return err
 			}
 
 			// Store into destination
-			if _, err := io.Copy(f, tr); err != nil {
+			if _, err := io.Copy(f, r); err != nil {
+				f.Close()
 				return err
 			}
 
-			err = f.Close()
-			if err != nil {
+			if err := f.Close(); err != nil {
 				return err
 			}
+			res.Written = append(res.Written, name)
 		}
+		return nil
+	})
+	if err != nil {
+		return &res, err
+	}
+	return &res, nil
+}
+
+// Conflicts returns the files in the archive that already exist at the destination
+func Conflicts(sourcetb string, out string, opts Options) ([]string, error) {
+	l, err := List(sourcetb, opts)
+	if err != nil {
+		return nil, err
+	}
+	return l.Against(out).Existing(), nil
+}
+
+func (opts Options) resolve(name string) (ConflictPolicy, error) {
+	if opts.OnConflict != ConflictAsk {
+		return opts.OnConflict, nil
+	}
+	policy, err := opts.Ask(name)
+	if err != nil {
+		return policy, err
+	}
+	if policy == ConflictAsk {
+		return policy, fmt.Errorf("no conflict policy chosen for %s", name)
+	}
+	return policy, nil
+}
+
+// backup moves the existing file out of the way to the first free .orig name
+func backup(target string) error {
+	dest := target + BackupSuffix
+	for i := 1; fileExists(dest); i++ {
+		dest = fmt.Sprintf("%s%s.%d", target, BackupSuffix, i)
+	}
+	return os.Rename(target, dest)
+}
+
+// walk is walkTarGz restricted to the selected entries, with names and
+// content as they will be in the destination. size is the size of the
+// content after any substitutions. Filtering happens while streaming so
+// nothing outside of the selection is ever read into the destination.
+func (opts Options) walk(sourcetb string, fn func(name string, hdr *tar.Header, size int64, r io.Reader) error) error {
+	rep := opts.replacer()
+	return walkTarGz(sourcetb, func(name string, hdr *tar.Header, r io.Reader) error {
+		target, ok := opts.target(name)
+		if !ok {
+			return nil
+		}
+		if rep == nil {
+			return fn(target, hdr, hdr.Size, r)
+		}
+		substituted, r, size, err := substitute(rep, target, hdr, r)
+		if err != nil {
+			return err
+		}
+		//a substituted name must stay inside the destination too
+		substituted, err = cleanName(substituted)
+		if err != nil {
+			return err
+		}
+		if substituted == "" {
+			return fmt.Errorf("archive entry %s is the destination itself after substitution", target)
+		}
+		return fn(substituted, hdr, size, r)
+	})
+}
+
+// walkTarGz calls fn for every entry of the tar.gz with its cleaned, slash
+// separated name. Entries are streamed, r is only valid during the call.
+func walkTarGz(sourcetb string, fn func(name string, hdr *tar.Header, r io.Reader) error) error {
+	tbz, err := os.Open(sourcetb)
+	if err != nil {
+		return err
+	}
+	defer tbz.Close()
+
+	gzr, err := gzip.NewReader(tbz)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+
+	for {
+		hdr, err := tr.Next()
+
+		switch {
+
+		case err == io.EOF:
+			return nil // return when no more files, good path
+
+		case err != nil:
+			return err
+		}
+
+		name, err := cleanName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue // the archive root itself, i.e. "./"
+		}
+		if err := fn(name, hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanName normalises an archive entry name, rejecting any that would
+// escape the destination directory
+func cleanName(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if rel := path.Clean(name); rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("archive entry %s is outside of the destination", name)
 	}
+	return strings.TrimPrefix(path.Clean("/"+name), "/"), nil
 }
 
 func fileExists(path string) bool {
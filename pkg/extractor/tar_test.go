@@ -3,11 +3,15 @@
 package extractor
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"crypto/sha512"
 	"encoding/hex"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -92,3 +96,387 @@ func TestOKTarGz(t *testing.T) {
 		t.Error(err) // Failed to run against ok-ish tar
 	}
 }
+
+// setupConflict extracts into a destination that already has a long testalpha.txt
+func setupConflict(t *testing.T) (output string, original []byte) {
+	t.Helper()
+	output = filepath.Join(setupGoldTemp(t), "conflict")
+	if err := os.MkdirAll(output, 0750); err != nil {
+		t.Fatal(err)
+	}
+	original = []byte("this is much longer than the file in the golden archive")
+	if err := ioutil.WriteFile(filepath.Join(output, testalpha), original, 0640); err != nil {
+		t.Fatal(err)
+	}
+	return output, original
+}
+
+func TestConflictPolicies(t *testing.T) {
+	golden := filepath.Join("testdata", "golden.tar.gz")
+
+	//Overwrite must truncate, not leave the tail of the longer file behind
+	output, _ := setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+	res, err := Extract(golden, output, Options{OnConflict: ConflictOverwrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := localHash(t, filepath.Join(output, testalpha)); sum != testalphaSum {
+		t.Errorf("overwritten alpha file does not match golden, old contents were left behind")
+	}
+	if len(res.Written) != 2 {
+		t.Errorf("expected both files to be written, got %v", res.Written)
+	}
+
+	//Skip leaves the file alone
+	output, original := setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+	res, err = Extract(golden, output, Options{OnConflict: ConflictSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(output, testalpha)); string(b) != string(original) {
+		t.Errorf("skip policy modified an existing file")
+	}
+	if len(res.Skipped) != 1 || res.Skipped[0] != testalpha {
+		t.Errorf("expected %s to be skipped, got %v", testalpha, res.Skipped)
+	}
+
+	//Backup moves the file aside
+	output, original = setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+	_, err = Extract(golden, output, Options{OnConflict: ConflictBackup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(output, testalpha+BackupSuffix)); string(b) != string(original) {
+		t.Errorf("backup policy did not preserve the existing file")
+	}
+	if sum := localHash(t, filepath.Join(output, testalpha)); sum != testalphaSum {
+		t.Errorf("backup policy did not extract the archive copy")
+	}
+
+	//Fail writes nothing at all
+	output, _ = setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+	_, err = Extract(golden, output, Options{OnConflict: ConflictFail})
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("expected a ConflictError, got %v", err)
+	}
+	if fileExists(filepath.Join(output, testdir, testbeta)) {
+		t.Errorf("fail policy should not have written any files")
+	}
+
+	//Ask is consulted for every colliding file
+	output, original = setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+	var asked []string
+	_, err = Extract(golden, output, Options{OnConflict: ConflictAsk, Ask: func(name string) (ConflictPolicy, error) {
+		asked = append(asked, name)
+		return ConflictSkip, nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 1 || asked[0] != testalpha {
+		t.Errorf("expected to be asked about %s only, was asked about %v", testalpha, asked)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	golden := filepath.Join("testdata", "golden.tar.gz")
+	output, _ := setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+
+	conflicts, err := Conflicts(golden, output, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != testalpha {
+		t.Errorf("expected only %s to conflict, got %v", testalpha, conflicts)
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, name := range ConflictPolicyNames() {
+		p, err := ParseConflictPolicy(name)
+		if err != nil {
+			t.Error(err)
+		}
+		if p.String() != name {
+			t.Errorf("policy %s round tripped to %s", name, p)
+		}
+	}
+	if _, err := ParseConflictPolicy("yolo"); err == nil {
+		t.Errorf("yolo is not a conflict policy")
+	}
+}
+
+func TestList(t *testing.T) {
+	golden := filepath.Join("testdata", "golden.tar.gz")
+	l, err := List(golden, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range l.Entries {
+		names = append(names, e.Name)
+	}
+	expected := []string{testdir, testdir + "/" + testbeta, testalpha}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected listing %v, got %v", expected, names)
+	}
+	if l.Entries[2].SHA512 != testalphaSum {
+		t.Errorf("listed alpha hash does not match golden %s, got %s", testalphaSum, l.Entries[2].SHA512)
+	}
+	if l.Files != 2 || l.TotalSize != 48 {
+		t.Errorf("expected 2 files totalling 48 bytes, got %d files totalling %d", l.Files, l.TotalSize)
+	}
+
+	//A listing must not touch the destination
+	output, _ := setupConflict(t)
+	defer cleanupTemp(t, filepath.Dir(output))
+	checked := l.Against(output)
+	if !reflect.DeepEqual(checked.Existing(), []string{testalpha}) {
+		t.Errorf("expected only %s to exist, got %v", testalpha, checked.Existing())
+	}
+	if fileExists(filepath.Join(output, testdir)) {
+		t.Errorf("listing created files in the destination")
+	}
+	if len(l.Existing()) != 0 {
+		t.Errorf("Against should not modify the original listing")
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	for size, expected := range map[int64]string{0: "0 B", 1023: "1023 B", 2048: "2.0 KiB", 5 << 20: "5.0 MiB"} {
+		if s := HumanSize(size); s != expected {
+			t.Errorf("HumanSize(%d) = %s, expected %s", size, s, expected)
+		}
+	}
+}
+
+func TestSelection(t *testing.T) {
+	nested := filepath.Join("testdata", "nested.tar.gz")
+
+	tempPath := setupGoldTemp(t)
+	defer cleanupTemp(t, tempPath)
+	output := filepath.Join(tempPath, "nested")
+
+	opts := Options{Include: []string{"wrapper/subA"}, Exclude: []string{"*.o"}, StripComponents: 2}
+	res, err := Extract(nested, output, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Written, []string{"a.txt"}) {
+		t.Errorf("expected only a.txt to be written, got %v", res.Written)
+	}
+
+	var found []string
+	filepath.Walk(output, func(p string, info os.FileInfo, err error) error {
+		if err == nil && p != output {
+			rel, _ := filepath.Rel(output, p)
+			found = append(found, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	//build itself is selected, only its contents are excluded
+	if !reflect.DeepEqual(found, []string{"a.txt", "build"}) {
+		t.Errorf("nothing outside of the selection should be written, found %v", found)
+	}
+
+	l, err := List(nested, Options{StripComponents: 1, Exclude: []string{"subB"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range l.Entries {
+		names = append(names, e.Name)
+	}
+	expected := []string{"README.md", "subA", "subA/a.txt", "subA/build", "subA/build/a.o"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected listing %v, got %v", expected, names)
+	}
+
+	if _, err := Extract(nested, output, Options{Include: []string{"[a-"}}); err == nil {
+		t.Errorf("a malformed pattern should be rejected")
+	}
+	if _, err := List(nested, Options{StripComponents: -1}); err == nil {
+		t.Errorf("negative strip components should be rejected")
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"wrapper/subA", "wrapper/subA/a.txt", true},
+		{"wrapper/subA/", "wrapper/subA", true},
+		{"wrapper/sub*/*.txt", "wrapper/subB/b.txt", true},
+		{"wrapper/**/a.o", "wrapper/subA/build/a.o", true},
+		{"**/build", "wrapper/subA/build/a.o", true},
+		{"*.o", "wrapper/subA/build/a.o", true},
+		{"*.o", "wrapper/subA/a.txt", false},
+		{"subA", "wrapper/subB/b.txt", false},
+		{"wrapper/subA", "wrapper/subAB/x", false},
+		{"", "wrapper", false},
+	}
+	for _, c := range cases {
+		if matchGlob(c.pattern, c.name) != c.match {
+			t.Errorf("matchGlob(%q, %q) should be %v", c.pattern, c.name, c.match)
+		}
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for name, expected := range map[string]string{"./a/b": "a/b", "./": "", "a//b/": "a/b"} {
+		clean, err := cleanName(name)
+		if err != nil || clean != expected {
+			t.Errorf("cleanName(%q) = %q, %v expected %q", name, clean, err, expected)
+		}
+	}
+	for _, name := range []string{"../evil", "a/../../evil", ".."} {
+		if _, err := cleanName(name); err == nil {
+			t.Errorf("%s escapes the destination and should be rejected", name)
+		}
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	golden := filepath.Join("testdata", "golden.tar.gz")
+	files, err := ReadFiles(golden, Options{Include: []string{testdir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the selected file, got %d", len(files))
+	}
+	beta, ok := files[testdir+"/"+testbeta]
+	if !ok {
+		t.Fatalf("%s was not read", testbeta)
+	}
+	sum := sha512.Sum512(beta.Data)
+	if hex.EncodeToString(sum[:]) != testbetaSum {
+		t.Errorf("read beta file does not match golden")
+	}
+	if beta.Mode != 0644 {
+		t.Errorf("expected mode 0644, got %v", beta.Mode)
+	}
+}
+
+func TestSubstitutions(t *testing.T) {
+	template := filepath.Join("testdata", "template.tar.gz")
+
+	tempPath := setupGoldTemp(t)
+	defer cleanupTemp(t, tempPath)
+	output := filepath.Join(tempPath, "template")
+
+	opts := Options{StripComponents: 1, Substitutions: []Substitution{{Old: "mandelbrot", New: "fractal"}}}
+	if _, err := Extract(template, output, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	cmake, err := ioutil.ReadFile(filepath.Join(output, "CMakeLists.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "cmake_minimum_required(VERSION 3.4)\nproject(fractal)\nadd_executable(fractal src/fractal.cpp)\n"
+	if string(cmake) != expected {
+		t.Errorf("expected content %q, got %q", expected, cmake)
+	}
+	if !fileExists(filepath.Join(output, "src", "fractal.cpp")) {
+		t.Errorf("file names should be substituted")
+	}
+	//binary content is left alone, only the name changes
+	bin, err := ioutil.ReadFile(filepath.Join(output, "fractal.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bin) != "mandelbrot\x00\x01\x02" {
+		t.Errorf("binary content should not be substituted, got %q", bin)
+	}
+
+	//listings report the substituted names and sizes
+	l, err := List(template, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range l.Entries {
+		if e.Name == "CMakeLists.txt" && e.Size != int64(len(expected)) {
+			t.Errorf("expected size %d after substitution, got %d", len(expected), e.Size)
+		}
+	}
+	if conflicts, _ := Conflicts(template, output, opts); len(conflicts) != 3 {
+		t.Errorf("expected the 3 substituted files to collide, got %v", conflicts)
+	}
+
+	bad := []Substitution{{Old: "", New: "x"}, {Old: "mandelbrot", New: "../x"}}
+	for _, s := range bad {
+		if _, err := List(template, Options{Substitutions: []Substitution{s}}); err == nil {
+			t.Errorf("substitution %v should be rejected", s)
+		}
+	}
+}
+
+// writeTarGz writes a tar.gz holding a file of each name
+func writeTarGz(t *testing.T, p string, names ...string) {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubstitutionTraversal(t *testing.T) {
+	tempPath := setupGoldTemp(t)
+	defer cleanupTemp(t, tempPath)
+	archive := filepath.Join(tempPath, "evil.tar.gz")
+	writeTarGz(t, archive, "NAME/escaped.txt", "NAME./escaped.txt", "a/NAME/b/escaped.txt")
+	output := filepath.Join(tempPath, "out")
+
+	for _, s := range []Substitution{
+		{Old: "NAME", New: ".."},
+		{Old: "NAME", New: "."},
+		{Old: "a/NAME", New: "../.."},
+		{Old: "a/NAME/b", New: "x/../../.."},
+	} {
+		if _, err := Extract(archive, output, Options{Substitutions: []Substitution{s}}); err == nil {
+			t.Errorf("substitution %v should be rejected", s)
+		}
+	}
+	if fileExists(filepath.Join(tempPath, "escaped.txt")) {
+		t.Fatalf("a file was written outside of the destination")
+	}
+
+	//names are checked again after substitution, whatever got them there
+	dotted := filepath.Join(tempPath, "dotted.tar.gz")
+	writeTarGz(t, dotted, "NAME./escaped.txt")
+	opts := Options{Substitutions: []Substitution{{Old: "NAME", New: "."}}}
+	err := opts.walk(dotted, func(name string, hdr *tar.Header, size int64, r io.Reader) error {
+		t.Errorf("%s should not be walked", name)
+		return nil
+	})
+	if err == nil {
+		t.Errorf("an entry escaping the destination after substitution should fail")
+	}
+}
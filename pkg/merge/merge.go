@@ -0,0 +1,233 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package merge implements a line based three-way merge
+package merge
+
+import (
+	"bytes"
+)
+
+// Labels are written after the conflict markers to name each side
+type Labels struct {
+	Ours   string
+	Base   string
+	Theirs string
+}
+
+// Merge combines the changes made from base to ours with the changes made
+// from base to theirs. Where both sides changed the same lines differently
+// diff3 style conflict markers are written, the number of conflicts is
+// returned alongside the merged content.
+func Merge(base, ours, theirs []byte, labels Labels) ([]byte, int) {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchA := matches(o, a)
+	matchB := matches(o, b)
+
+	var out bytes.Buffer
+	conflicts := 0
+	i, ia, ib := 0, 0, 0 // start of the current chunk in base, ours and theirs
+
+	for {
+		//find the next base line kept by both sides
+		k := i
+		for k < len(o) && (matchA[k] < 0 || matchB[k] < 0) {
+			k++
+		}
+
+		endA, endB := len(a), len(b)
+		if k < len(o) {
+			endA, endB = matchA[k], matchB[k]
+		}
+		if mergeChunk(&out, o[i:k], a[ia:endA], b[ib:endB], labels) {
+			conflicts++
+		}
+
+		if k == len(o) {
+			return out.Bytes(), conflicts
+		}
+		out.Write(o[k])
+		i, ia, ib = k+1, endA+1, endB+1
+	}
+}
+
+// mergeChunk writes the resolution of a chunk where base, ours and theirs
+// may differ, returning true for a conflict
+func mergeChunk(out *bytes.Buffer, base, ours, theirs [][]byte, labels Labels) bool {
+	switch {
+	case equalLines(ours, base):
+		writeLines(out, theirs)
+	case equalLines(theirs, base), equalLines(ours, theirs):
+		writeLines(out, ours)
+	default:
+		writeMarker(out, "<<<<<<<", labels.Ours)
+		writeLines(out, ours)
+		writeMarker(out, "|||||||", labels.Base)
+		writeLines(out, base)
+		writeMarker(out, "=======", "")
+		writeLines(out, theirs)
+		writeMarker(out, ">>>>>>>", labels.Theirs)
+		return true
+	}
+	return false
+}
+
+func writeMarker(out *bytes.Buffer, marker string, label string) {
+	//conflict markers need to start on their own line
+	if out.Len() > 0 && out.Bytes()[out.Len()-1] != '\n' {
+		out.WriteByte('\n')
+	}
+	out.WriteString(marker)
+	if label != "" {
+		out.WriteString(" " + label)
+	}
+	out.WriteByte('\n')
+}
+
+func writeLines(out *bytes.Buffer, lines [][]byte) {
+	for _, l := range lines {
+		out.Write(l)
+	}
+}
+
+func equalLines(x, y [][]byte) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !bytes.Equal(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLines splits after every newline, keeping the line endings so the
+// content is reproduced exactly
+func splitLines(b []byte) [][]byte {
+	var lines [][]byte
+	for len(b) > 0 {
+		n := bytes.IndexByte(b, '\n')
+		if n < 0 {
+			lines = append(lines, b)
+			break
+		}
+		lines = append(lines, b[:n+1])
+		b = b[n+1:]
+	}
+	return lines
+}
+
+// matches returns for every line of x the index of the line of y it is
+// paired with in a longest common subsequence, or -1
+func matches(x, y [][]byte) []int {
+	m := make([]int, len(x))
+	for i := range m {
+		m[i] = -1
+	}
+	for _, p := range lcs(x, y) {
+		m[p[0]] = p[1]
+	}
+	return m
+}
+
+// lcs finds a longest common subsequence of lines with Myers' algorithm,
+// returned as pairs of matching indexes
+func lcs(x, y [][]byte) [][2]int {
+	//common prefix and suffix are trivially matched
+	var pairs [][2]int
+	start := 0
+	for start < len(x) && start < len(y) && bytes.Equal(x[start], y[start]) {
+		pairs = append(pairs, [2]int{start, start})
+		start++
+	}
+	endX, endY := len(x), len(y)
+	var suffix [][2]int
+	for endX > start && endY > start && bytes.Equal(x[endX-1], y[endY-1]) {
+		endX--
+		endY--
+		suffix = append([][2]int{{endX, endY}}, suffix...)
+	}
+
+	for _, p := range myers(x[start:endX], y[start:endY]) {
+		pairs = append(pairs, [2]int{p[0] + start, p[1] + start})
+	}
+	return append(pairs, suffix...)
+}
+
+func myers(x, y [][]byte) [][2]int {
+	n, m := len(x), len(y)
+	if n == 0 || m == 0 {
+		return nil
+	}
+	max := n + m
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var px int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				px = v[offset+k+1]
+			} else {
+				px = v[offset+k-1] + 1
+			}
+			py := px - k
+			for px < n && py < m && bytes.Equal(x[px], y[py]) {
+				px++
+				py++
+			}
+			v[offset+k] = px
+			if px >= n && py >= m {
+				return backtrack(trace, x, y, offset, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the recorded Myers frontiers back from the end to
+// recover the matched (diagonal) moves
+func backtrack(trace [][]int, x, y [][]byte, offset int, d int) [][2]int {
+	var pairs [][2]int
+	px, py := len(x), len(y)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := px - py
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for px > prevX && py > prevY {
+			px--
+			py--
+			pairs = append(pairs, [2]int{px, py})
+		}
+		px, py = prevX, prevY
+	}
+	for px > 0 && py > 0 {
+		px--
+		py--
+		pairs = append(pairs, [2]int{px, py})
+	}
+
+	//collected back to front
+	for i, j := 0, len(pairs)-1; i < j; i, j = i+1, j-1 {
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	return pairs
+}
+
+// IsBinary reports content that should not be merged line by line
+func IsBinary(b []byte) bool {
+	if len(b) > 8000 {
+		b = b[:8000]
+	}
+	return bytes.IndexByte(b, 0) >= 0
+}
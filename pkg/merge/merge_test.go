@@ -0,0 +1,109 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package merge
+
+import (
+	"strings"
+	"testing"
+)
+
+var testLabels = Labels{Ours: "local", Base: "original", Theirs: "sample"}
+
+func lines(l ...string) []byte {
+	return []byte(strings.Join(l, "\n") + "\n")
+}
+
+func TestMergeClean(t *testing.T) {
+	base := lines("a", "b", "c", "d", "e")
+	ours := lines("a", "B", "c", "d", "e")
+	theirs := lines("a", "b", "c", "d", "E", "f")
+
+	merged, conflicts := Merge(base, ours, theirs, testLabels)
+	if conflicts != 0 {
+		t.Errorf("expected no conflicts, got %d", conflicts)
+	}
+	if expected := lines("a", "B", "c", "d", "E", "f"); string(merged) != string(expected) {
+		t.Errorf("expected\n%s\ngot\n%s", expected, merged)
+	}
+}
+
+func TestMergeSameChange(t *testing.T) {
+	base := lines("a", "b", "c")
+	both := lines("a", "x", "c")
+
+	merged, conflicts := Merge(base, both, both, testLabels)
+	if conflicts != 0 || string(merged) != string(both) {
+		t.Errorf("identical changes should merge cleanly, got %d conflicts\n%s", conflicts, merged)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	base := lines("a", "b", "c")
+	ours := lines("a", "mine", "c")
+	theirs := lines("a", "yours", "c")
+
+	merged, conflicts := Merge(base, ours, theirs, testLabels)
+	if conflicts != 1 {
+		t.Errorf("expected a conflict, got %d", conflicts)
+	}
+	expected := lines("a", "<<<<<<< local", "mine", "||||||| original", "b", "=======", "yours", ">>>>>>> sample", "c")
+	if string(merged) != string(expected) {
+		t.Errorf("expected\n%s\ngot\n%s", expected, merged)
+	}
+}
+
+func TestMergeDeletions(t *testing.T) {
+	base := lines("a", "b", "c", "d")
+	ours := lines("a", "c", "d")
+	theirs := lines("a", "b", "c")
+
+	merged, conflicts := Merge(base, ours, theirs, testLabels)
+	if conflicts != 0 {
+		t.Errorf("expected no conflicts, got %d", conflicts)
+	}
+	if expected := lines("a", "c"); string(merged) != string(expected) {
+		t.Errorf("expected\n%s\ngot\n%s", expected, merged)
+	}
+}
+
+func TestMergeNoTrailingNewline(t *testing.T) {
+	base := []byte("a\nb")
+	ours := []byte("a\nb")
+	theirs := []byte("a\nb\nc")
+
+	merged, conflicts := Merge(base, ours, theirs, testLabels)
+	if conflicts != 0 || string(merged) != "a\nb\nc" {
+		t.Errorf("expected a clean merge, got %d conflicts\n%q", conflicts, merged)
+	}
+
+	merged, conflicts = Merge([]byte("x"), []byte("y"), []byte("z"), testLabels)
+	if conflicts != 1 || !strings.Contains(string(merged), "y\n|||||||") {
+		t.Errorf("conflict markers must start on their own line\n%q", merged)
+	}
+}
+
+func TestLCS(t *testing.T) {
+	x := splitLines(lines("a", "b", "c", "a", "b", "b", "a"))
+	y := splitLines(lines("c", "b", "a", "b", "a", "c"))
+	pairs := lcs(x, y)
+	if len(pairs) != 4 {
+		t.Errorf("expected a common subsequence of 4 lines, got %v", pairs)
+	}
+	for i, p := range pairs {
+		if string(x[p[0]]) != string(y[p[1]]) {
+			t.Errorf("paired lines %v differ", p)
+		}
+		if i > 0 && (p[0] <= pairs[i-1][0] || p[1] <= pairs[i-1][1]) {
+			t.Errorf("pairs are not increasing %v", pairs)
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if IsBinary([]byte("hello\n")) {
+		t.Errorf("text is not binary")
+	}
+	if !IsBinary([]byte{'E', 'L', 'F', 0}) {
+		t.Errorf("NUL bytes mean binary")
+	}
+}
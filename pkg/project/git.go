@@ -0,0 +1,99 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Used as the commit author when no git identity is configured
+const (
+	defaultGitName  = "oneapi-cli"
+	defaultGitEmail = "oneapi-cli@localhost"
+)
+
+// InitGit turns a created project into a git repository whose first commit
+// holds the pristine sample, so later changes are a plain git diff away. It
+// does not need a git binary. When root is already inside a repository
+// nothing is done and false is returned.
+func InitGit(root string) (bool, error) {
+	if _, err := git.PlainOpenWithOptions(root, &git.PlainOpenOptions{DetectDotGit: true}); err == nil {
+		return false, nil
+	}
+
+	m, err := ReadManifest(root)
+	if err != nil {
+		return false, err
+	}
+
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		return false, err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+
+	//Only the files still matching the sample are pristine, anything kept
+	//from before the project was created is left for the user to add
+	var names []string
+	for name, sum := range m.Files {
+		if h, err := hashFile(filepath.Join(root, filepath.FromSlash(name))); err == nil && h == sum {
+			names = append(names, name)
+		}
+	}
+	names = append(names, ManifestName)
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := w.Add(name); err != nil {
+			return true, fmt.Errorf("failed to add %s to the repository - %v", name, err)
+		}
+	}
+
+	_, err = w.Commit(commitMessage(m), &git.CommitOptions{Author: gitAuthor()})
+	if err != nil {
+		return true, fmt.Errorf("failed to commit the sample - %v", err)
+	}
+	return true, nil
+}
+
+func commitMessage(m *Manifest) string {
+	name := m.Sample.Name
+	if name == "" {
+		name = m.Sample.Path
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Create project from oneAPI sample %s\n\n", name)
+	fmt.Fprintf(&b, "Sample: %s\n", name)
+	fmt.Fprintf(&b, "Path: %s\n", m.Sample.Path)
+	fmt.Fprintf(&b, "SHA: %s\n", m.Sample.SHA)
+	fmt.Fprintf(&b, "Language: %s\n", m.Sample.Language)
+	fmt.Fprintf(&b, "Source: %s\n", m.Sample.URL)
+	return b.String()
+}
+
+// gitAuthor uses the global git identity when there is one
+func gitAuthor() *object.Signature {
+	sig := &object.Signature{Name: defaultGitName, Email: defaultGitEmail, When: time.Now()}
+	cfg, err := config.LoadConfig(config.GlobalScope)
+	if err != nil {
+		return sig
+	}
+	if cfg.User.Name != "" {
+		sig.Name = cfg.User.Name
+	}
+	if cfg.User.Email != "" {
+		sig.Email = cfg.User.Email
+	}
+	return sig
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+func TestInitGit(t *testing.T) {
+	dir := setupProjectTemp(t)
+	defer os.RemoveAll(dir)
+
+	//A file that was there before the project was created
+	ioutil.WriteFile(filepath.Join(dir, testalpha), []byte("mine"), 0644)
+
+	tarPath := filepath.Join("testdata", "sample.tar.gz")
+	if _, err := Create(tarPath, dir, testSource, extractor.Options{OnConflict: extractor.ConflictSkip}, "devel"); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := InitGit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatalf("repository was not created")
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{testSource.Path, "SHA: " + testSource.SHA} {
+		if !strings.Contains(commit.Message, want) {
+			t.Errorf("commit message is missing %s:\n%s", want, commit.Message)
+		}
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.File(testbeta); err != nil {
+		t.Errorf("%s was not committed - %v", testbeta, err)
+	}
+	if _, err := tree.File(ManifestName); err != nil {
+		t.Errorf("%s was not committed - %v", ManifestName, err)
+	}
+	if _, err := tree.File(testalpha); err == nil {
+		t.Errorf("%s is not part of the pristine sample and should not be committed", testalpha)
+	}
+
+	//Already inside a repository, must not fail nor nest another one
+	nested := filepath.Join(dir, "nested")
+	if _, err := Create(tarPath, nested, testSource, extractor.Options{}, "devel"); err != nil {
+		t.Fatal(err)
+	}
+	created, err = InitGit(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || fileExists(filepath.Join(nested, ".git")) {
+		t.Errorf("should not create a repository inside another one")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+// ManifestName is the provenance file written into the root of a created project
+const ManifestName = ".oneapi-sample.json"
+
+// ManifestVersion is the version of the manifest format written by this CLI
+const ManifestVersion = 1
+
+// Source identifies the sample a project was created from
+type Source struct {
+	Name         string `json:"name,omitempty"`
+	URL          string `json:"url"`
+	Language     string `json:"language"`
+	Path         string `json:"path"`
+	SHA          string `json:"sha"`
+	IndexVersion string `json:"indexVersion,omitempty"`
+}
+
+// Selection records which part of the sample was extracted
+type Selection struct {
+	Include         []string `json:"include,omitempty"`
+	Exclude         []string `json:"exclude,omitempty"`
+	StripComponents int      `json:"stripComponents,omitempty"`
+
+	Substitutions []extractor.Substitution `json:"substitutions,omitempty"`
+}
+
+// Manifest records where a project came from, Files maps every file of the
+// sample (slash separated, relative to the project root) to its sha512
+type Manifest struct {
+	Version    int               `json:"version"`
+	Sample     Source            `json:"sample"`
+	Selection  Selection         `json:"selection"`
+	CLIVersion string            `json:"cliVersion"`
+	Created    time.Time         `json:"created"`
+	Upgraded   *time.Time        `json:"upgraded,omitempty"`
+	Files      map[string]string `json:"files"`
+}
+
+// NewManifest describes a project created from the tarball with opts
+func NewManifest(src Source, tarPath string, opts extractor.Options, cliVersion string) (*Manifest, error) {
+	l, err := extractor.List(tarPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Version:    ManifestVersion,
+		Sample:     src,
+		Selection:  SelectionOf(opts),
+		CLIVersion: cliVersion,
+		Created:    time.Now().UTC(),
+		Files:      make(map[string]string),
+	}
+	for _, e := range l.Entries {
+		if !e.Dir && e.Name != ManifestName {
+			m.Files[e.Name] = e.SHA512
+		}
+	}
+	return m, nil
+}
+
+// SelectionOf returns the selection part of extractor options
+func SelectionOf(opts extractor.Options) Selection {
+	return Selection{Include: opts.Include, Exclude: opts.Exclude, StripComponents: opts.StripComponents, Substitutions: opts.Substitutions}
+}
+
+// Options returns extractor options reproducing the recorded selection
+func (s Selection) Options() extractor.Options {
+	return extractor.Options{Include: s.Include, Exclude: s.Exclude, StripComponents: s.StripComponents, Substitutions: s.Substitutions}
+}
+
+// Write stores the manifest in the root of the project
+func (m *Manifest) Write(root string) error {
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(root, ManifestName), append(b, '\n'), 0644)
+}
+
+// ReadManifest reads the manifest from the root of a project
+func ReadManifest(root string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(filepath.Join(root, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s - %v", ManifestName, err)
+	}
+	if m.Version > ManifestVersion {
+		return nil, fmt.Errorf("%s was written by a newer CLI (version %d), please update", ManifestName, m.Version)
+	}
+	return &m, nil
+}
+
+// Create extracts the sample tarball into dest and records its provenance
+func Create(tarPath string, dest string, src Source, opts extractor.Options, cliVersion string) (*extractor.Result, error) {
+	m, err := NewManifest(src, tarPath, opts, cliVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := extractor.Extract(tarPath, dest, opts)
+	if err != nil {
+		return res, err
+	}
+
+	if err := m.Write(dest); err != nil {
+		return res, fmt.Errorf("created project but failed to record where it came from - %v", err)
+	}
+	return res, nil
+}
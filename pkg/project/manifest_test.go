@@ -0,0 +1,79 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+// testdata/sample.tar.gz contents
+const testalpha = "testalpha.txt"
+const testbeta = "dirtest/testbeta.txt"
+const testalphaSum = "10b8eefa145e6f3ff612197247765c0fa15788874b2f483879c8528383489d97f4ac18daed45334341d55342c94602976b401c88e879a9d8fd950c69040e29e2"
+
+var testSource = Source{URL: "http://example.com/samples", Language: "cpp", Path: "zoo/zebra", SHA: "1", IndexVersion: "abc"}
+
+func setupProjectTemp(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCreate(t *testing.T) {
+	dir := setupProjectTemp(t)
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "zebra")
+
+	tarPath := filepath.Join("testdata", "sample.tar.gz")
+	_, err := Create(tarPath, dest, testSource, extractor.Options{Exclude: []string{"dirtest"}}, "devel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadManifest(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Sample != testSource {
+		t.Errorf("recorded sample %v does not match %v", m.Sample, testSource)
+	}
+	if m.CLIVersion != "devel" || m.Version != ManifestVersion || m.Created.IsZero() {
+		t.Errorf("manifest is missing its metadata %v", m)
+	}
+	if len(m.Files) != 1 || m.Files[testalpha] != testalphaSum {
+		t.Errorf("expected only %s to be recorded with its hash, got %v", testalpha, m.Files)
+	}
+	if _, ok := m.Files[testbeta]; ok {
+		t.Errorf("excluded files should not be recorded")
+	}
+	if len(m.Selection.Exclude) != 1 {
+		t.Errorf("selection was not recorded %v", m.Selection)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	dir := setupProjectTemp(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := ReadManifest(dir); err == nil {
+		t.Errorf("there is no manifest to read")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, ManifestName), []byte("{not json"), 0644)
+	if _, err := ReadManifest(dir); err == nil {
+		t.Errorf("a broken manifest should fail to read")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, ManifestName), []byte(`{"version": 999}`), 0644)
+	if _, err := ReadManifest(dir); err == nil {
+		t.Errorf("a manifest from the future should fail to read")
+	}
+}
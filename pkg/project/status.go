@@ -0,0 +1,183 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package project
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+// Changes lists the files of a project that differ from the sample, names
+// are slash separated and relative to the project root
+type Changes struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Deleted  []string `json:"deleted"`
+}
+
+// Clean is true when the project matches the sample exactly
+func (c *Changes) Clean() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Deleted) == 0
+}
+
+// Status is the state of a project compared to the sample it was created from
+type Status struct {
+	Root   string `json:"root"`
+	Sample Source `json:"sample"`
+	Changes
+	LatestSHA       string `json:"latestSHA,omitempty"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+}
+
+// FindRoot searches dir and its parents for a project manifest
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if fileExists(filepath.Join(dir, ManifestName)) {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("not inside a project created by oneapi-cli, no %s found", ManifestName)
+		}
+		dir = parent
+	}
+}
+
+// FileHashes returns the sha512 of every file the tarball gives with opts,
+// it is used for projects whose manifest does not list its files
+func FileHashes(tarPath string, opts extractor.Options) (map[string]string, error) {
+	l, err := extractor.List(tarPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, e := range l.Entries {
+		if !e.Dir {
+			files[e.Name] = e.SHA512
+		}
+	}
+	return files, nil
+}
+
+// Diff compares the files under root with the recorded sample files
+func Diff(root string, files map[string]string) (*Changes, error) {
+	c := Changes{Added: []string{}, Modified: []string{}, Deleted: []string{}}
+	seen := make(map[string]bool)
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if name == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name == ManifestName || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		original, ok := files[name]
+		if !ok {
+			c.Added = append(c.Added, name)
+			return nil
+		}
+		seen[name] = true
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		if sum != original {
+			c.Modified = append(c.Modified, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range files {
+		if !seen[name] {
+			c.Deleted = append(c.Deleted, name)
+		}
+	}
+	sort.Strings(c.Deleted)
+	return &c, nil
+}
+
+func hashBytes(b []byte) string {
+	sum := sha512.Sum512(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha512.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func fileExists(path string) bool {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false
+	}
+	return true
+}
+
+// Compare lists the files added, modified and deleted going from one
+// version of a sample to another
+func Compare(oldTar string, newTar string, opts extractor.Options) (*Changes, error) {
+	oldFiles, err := FileHashes(oldTar, opts)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := FileHashes(newTar, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Changes{Added: []string{}, Modified: []string{}, Deleted: []string{}}
+	for name, sum := range newFiles {
+		old, ok := oldFiles[name]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, name)
+		case old != sum:
+			c.Modified = append(c.Modified, name)
+		}
+	}
+	for name := range oldFiles {
+		if _, ok := newFiles[name]; !ok {
+			c.Deleted = append(c.Deleted, name)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Modified)
+	sort.Strings(c.Deleted)
+	return &c, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+func TestDiff(t *testing.T) {
+	dir := setupProjectTemp(t)
+	defer os.RemoveAll(dir)
+
+	tarPath := filepath.Join("testdata", "sample.tar.gz")
+	if _, err := Create(tarPath, dir, testSource, extractor.Options{}, "devel"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Diff(dir, m.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Clean() {
+		t.Errorf("freshly created project should be clean, got %v", c)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, testalpha), []byte("changed"), 0644)
+	os.Remove(filepath.Join(dir, filepath.FromSlash(testbeta)))
+	ioutil.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0750)
+	ioutil.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0644)
+
+	c, err = Diff(dir, m.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Changes{Added: []string{"new.txt"}, Modified: []string{testalpha}, Deleted: []string{testbeta}}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("expected %v, got %v", expected, c)
+	}
+
+	//The tarball gives the same baseline as the manifest
+	files, err := FileHashes(tarPath, m.Selection.Options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(files, m.Files) {
+		t.Errorf("tarball hashes %v do not match the manifest %v", files, m.Files)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	dir := setupProjectTemp(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := FindRoot(dir); err == nil {
+		t.Errorf("there is no project in %s", dir)
+	}
+
+	if err := (&Manifest{Version: ManifestVersion}).Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	deep := filepath.Join(dir, "a", "b")
+	os.MkdirAll(deep, 0750)
+
+	root, err := FindRoot(deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := filepath.Abs(dir)
+	if root != expected {
+		t.Errorf("expected root %s, got %s", expected, root)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c, err := Compare(filepath.Join("testdata", "v1.tar.gz"), filepath.Join("testdata", "v2.tar.gz"), extractor.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Changes{
+		Added:    []string{"new.txt"},
+		Modified: []string{"conflict.txt", "merge.txt", "update.txt"},
+		Deleted:  []string{"gone.txt"},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("expected %v, got %v", expected, c)
+	}
+}
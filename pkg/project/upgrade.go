@@ -0,0 +1,183 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/intel/oneapi-cli/pkg/extractor"
+	"github.com/intel/oneapi-cli/pkg/merge"
+)
+
+// Actions an upgrade takes on a file
+const (
+	ActionUpdated  = "updated"  // unmodified locally, replaced by the new version
+	ActionAdded    = "added"    // new in the sample
+	ActionRemoved  = "removed"  // removed from the sample and unmodified locally
+	ActionMerged   = "merged"   // changed on both sides and merged cleanly
+	ActionConflict = "conflict" // needs attention, see the detail
+)
+
+// FileUpgrade is what an upgrade did to a single file
+type FileUpgrade struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// UpgradeReport lists every file an upgrade touched or could not resolve
+type UpgradeReport struct {
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Files []FileUpgrade `json:"files"`
+}
+
+// Conflicts is the number of files that need attention
+func (r *UpgradeReport) Conflicts() int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Action == ActionConflict {
+			n++
+		}
+	}
+	return n
+}
+
+// Upgrade applies the changes between the sample version the project was
+// created from (oldTar) and a newer version (newTar) to the project at root,
+// keeping local modifications. Text files changed on both sides are merged
+// line by line, with conflict markers where the changes overlap. Unless
+// dryRun is set the manifest is updated to record the new version.
+func Upgrade(root string, m *Manifest, oldTar string, newTar string, to Source, dryRun bool) (*UpgradeReport, error) {
+	opts := m.Selection.Options()
+	oldFiles, err := extractor.ReadFiles(oldTar, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the original sample - %v", err)
+	}
+	newFiles, err := extractor.ReadFiles(newTar, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the new sample - %v", err)
+	}
+
+	var names []string
+	for name := range oldFiles {
+		names = append(names, name)
+	}
+	for name := range newFiles {
+		if _, ok := oldFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	labels := merge.Labels{Ours: "local", Base: "sample " + m.Sample.SHA, Theirs: "sample " + to.SHA}
+	report := &UpgradeReport{From: m.Sample.SHA, To: to.SHA}
+
+	for _, name := range names {
+		if name == ManifestName {
+			continue
+		}
+		f, data, err := upgradeFile(root, name, oldFiles, newFiles, labels)
+		if err != nil {
+			return report, err
+		}
+		if f == nil {
+			continue
+		}
+		report.Files = append(report.Files, *f)
+		if dryRun {
+			continue
+		}
+		if err := applyUpgrade(root, *f, data, newFiles[name].Mode); err != nil {
+			return report, err
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	now := time.Now().UTC()
+	m.Sample = to
+	m.Upgraded = &now
+	m.Files = make(map[string]string)
+	for name, f := range newFiles {
+		m.Files[name] = hashBytes(f.Data)
+	}
+	return report, m.Write(root)
+}
+
+// upgradeFile decides what happens to a single file, returning the content
+// to write. A nil FileUpgrade means nothing needs to happen.
+func upgradeFile(root string, name string, oldFiles, newFiles map[string]extractor.File, labels merge.Labels) (*FileUpgrade, []byte, error) {
+	base, inOld := oldFiles[name]
+	theirs, inNew := newFiles[name]
+
+	ours, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	local := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	switch {
+	case inOld && inNew && bytes.Equal(base.Data, theirs.Data):
+		return nil, nil, nil // unchanged in the sample
+	case local && inNew && bytes.Equal(ours, theirs.Data):
+		return nil, nil, nil // already up to date
+
+	case !inNew:
+		if !local {
+			return nil, nil, nil
+		}
+		if bytes.Equal(ours, base.Data) {
+			return &FileUpgrade{Name: name, Action: ActionRemoved}, nil, nil
+		}
+		return &FileUpgrade{Name: name, Action: ActionConflict, Detail: "removed from the sample but modified locally, local version kept"}, nil, nil
+
+	case !local:
+		if inOld {
+			return &FileUpgrade{Name: name, Action: ActionConflict, Detail: "deleted locally but changed in the sample, not restored"}, nil, nil
+		}
+		return &FileUpgrade{Name: name, Action: ActionAdded}, theirs.Data, nil
+
+	case inOld && bytes.Equal(ours, base.Data):
+		return &FileUpgrade{Name: name, Action: ActionUpdated}, theirs.Data, nil
+	}
+
+	//Changed on both sides, or added on both sides
+	if merge.IsBinary(ours) || merge.IsBinary(theirs.Data) || merge.IsBinary(base.Data) {
+		return &FileUpgrade{Name: name, Action: ActionConflict, Detail: "binary file changed on both sides, local version kept"}, nil, nil
+	}
+	merged, conflicts := merge.Merge(base.Data, ours, theirs.Data, labels)
+	if merged == nil {
+		merged = []byte{} // both sides emptied it, still needs writing
+	}
+	if conflicts > 0 {
+		return &FileUpgrade{Name: name, Action: ActionConflict, Detail: fmt.Sprintf("%d conflicting change(s) marked in the file", conflicts)}, merged, nil
+	}
+	return &FileUpgrade{Name: name, Action: ActionMerged}, merged, nil
+}
+
+func applyUpgrade(root string, f FileUpgrade, data []byte, mode os.FileMode) error {
+	target := filepath.Join(root, filepath.FromSlash(f.Name))
+	if f.Action == ActionRemoved {
+		return os.Remove(target)
+	}
+	if data == nil {
+		return nil // conflicts left for the user
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return err
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+	return ioutil.WriteFile(target, data, mode)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+func readTestFile(t *testing.T, dir string, name string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUpgrade(t *testing.T) {
+	dir := setupProjectTemp(t)
+	defer os.RemoveAll(dir)
+
+	v1 := filepath.Join("testdata", "v1.tar.gz")
+	v2 := filepath.Join("testdata", "v2.tar.gz")
+	if _, err := Create(v1, dir, testSource, extractor.Options{}, "devel"); err != nil {
+		t.Fatal(err)
+	}
+
+	//Local customisations
+	ioutil.WriteFile(filepath.Join(dir, "merge.txt"), []byte("one\n2\n3\n4\n5\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "conflict.txt"), []byte("ours\n"), 0644)
+
+	m, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := testSource
+	to.SHA = "2"
+
+	//A dry run changes nothing
+	report, err := Upgrade(dir, m, v1, v2, to, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readTestFile(t, dir, "update.txt") != "one\n" || fileExists(filepath.Join(dir, "new.txt")) {
+		t.Errorf("dry run modified the project")
+	}
+
+	report, err = Upgrade(dir, m, v1, v2, to, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := make(map[string]string)
+	for _, f := range report.Files {
+		actions[f.Name] = f.Action
+	}
+	expected := map[string]string{
+		"conflict.txt": ActionConflict,
+		"gone.txt":     ActionRemoved,
+		"merge.txt":    ActionMerged,
+		"new.txt":      ActionAdded,
+		"update.txt":   ActionUpdated,
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("expected %v, got %v", expected, actions)
+	}
+	if report.Conflicts() != 1 {
+		t.Errorf("expected 1 conflict, got %d", report.Conflicts())
+	}
+
+	if s := readTestFile(t, dir, "merge.txt"); s != "one\n2\n3\n4\nfive\n" {
+		t.Errorf("merge.txt did not keep both changes:\n%s", s)
+	}
+	if s := readTestFile(t, dir, "update.txt"); s != "two\n" {
+		t.Errorf("update.txt was not updated:\n%s", s)
+	}
+	if s := readTestFile(t, dir, "conflict.txt"); !strings.Contains(s, "<<<<<<< local\nours\n") || !strings.Contains(s, "theirs\n>>>>>>> sample 2\n") {
+		t.Errorf("conflict.txt is missing its conflict markers:\n%s", s)
+	}
+	if fileExists(filepath.Join(dir, "gone.txt")) {
+		t.Errorf("gone.txt should have been removed")
+	}
+
+	//The project now records the new version as its baseline
+	m, err = ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Sample.SHA != "2" || m.Upgraded == nil {
+		t.Errorf("manifest does not record the upgrade %v", m)
+	}
+	c, err := Diff(dir, m.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Modified, []string{"conflict.txt", "merge.txt"}) || len(c.Added)+len(c.Deleted) != 0 {
+		t.Errorf("expected only the locally changed files to differ from the new version, got %v", c)
+	}
+}
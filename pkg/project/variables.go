@@ -0,0 +1,66 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+// Substitutions turns the values chosen for a sample's template variables
+// into extractor substitutions, variables without a value keep their
+// default. Values for variables the sample does not declare are an error.
+func Substitutions(vars []aggregator.TemplateVariable, values map[string]string) ([]extractor.Substitution, error) {
+	declared := make(map[string]bool)
+	var names []string
+	for _, v := range vars {
+		declared[v.Name] = true
+		names = append(names, v.Name)
+	}
+	for name := range values {
+		if declared[name] {
+			continue
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown variable '%s', the sample does not declare any", name)
+		}
+		return nil, fmt.Errorf("unknown variable '%s', the sample declares: %s", name, strings.Join(names, ", "))
+	}
+
+	var subs []extractor.Substitution
+	for _, v := range vars {
+		value, ok := values[v.Name]
+		if !ok {
+			value = v.Default
+		}
+		if value == "" {
+			return nil, fmt.Errorf("variable '%s' can not be empty", v.Name)
+		}
+		if value != v.Match {
+			subs = append(subs, extractor.Substitution{Old: v.Match, New: value})
+		}
+	}
+	//the longest match wins where one match contains another
+	sort.SliceStable(subs, func(i, j int) bool {
+		return len(subs[i].Old) > len(subs[j].Old)
+	})
+	return subs, nil
+}
+
+// ParseValues parses key=value pairs, i.e. from the command line
+func ParseValues(pairs []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, p := range pairs {
+		i := strings.Index(p, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected key=value, got '%s'", p)
+		}
+		values[p[:i]] = p[i+1:]
+	}
+	return values, nil
+}
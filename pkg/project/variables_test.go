@@ -0,0 +1,56 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package project
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/extractor"
+)
+
+func TestSubstitutions(t *testing.T) {
+	vars := []aggregator.TemplateVariable{
+		{Name: "PROJECT", Match: "mandel", Default: "mandel"},
+		{Name: "TARGET", Match: "mandel_bin", Default: "mandel_bin"},
+		{Name: "OUT", Match: "@OUT@", Default: "build"},
+	}
+
+	subs, err := Substitutions(vars, map[string]string{"PROJECT": "fractal", "TARGET": "fractal_bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []extractor.Substitution{
+		{Old: "mandel_bin", New: "fractal_bin"},
+		{Old: "mandel", New: "fractal"},
+		{Old: "@OUT@", New: "build"},
+	}
+	if !reflect.DeepEqual(subs, expected) {
+		t.Errorf("expected %v, got %v", expected, subs)
+	}
+
+	if _, err := Substitutions(vars, map[string]string{"NAME": "x"}); err == nil {
+		t.Errorf("undeclared variables should be rejected")
+	}
+	if _, err := Substitutions(vars, map[string]string{"PROJECT": ""}); err == nil {
+		t.Errorf("empty values should be rejected")
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	values, err := ParseValues([]string{"PROJECT=fractal", "FLAGS=-O2 -g=x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"PROJECT": "fractal", "FLAGS": "-O2 -g=x"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+	for _, bad := range []string{"PROJECT", "=fractal"} {
+		if _, err := ParseValues([]string{bad}); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/intel/oneapi-cli/pkg/browser"
 	"github.com/intel/oneapi-cli/pkg/deps"
 	"github.com/intel/oneapi-cli/pkg/extractor"
+	"github.com/intel/oneapi-cli/pkg/project"
 	"gitlab.com/tslocum/cview"
 )
 
@@ -32,8 +35,13 @@ type CLI struct {
 	aggregator *aggregator.Aggregator
 	userHome   string
 	oneAPIRoot string
+	installs   []deps.Installation // oneAPI installations found on the machine
 	home       *cview.List
 	langSelect *cview.List
+	version    string
+	gitInit    bool
+	values     map[string]string // template variable values for valuesFor
+	valuesFor  string
 }
 
 const idzURL = "https://www.intel.com/content/www/us/en/developer/tools/oneapi/overview"
@@ -52,16 +60,29 @@ func NewCLI(a *aggregator.Aggregator, uH string) (cli *CLI, err error) {
 		return nil, fmt.Errorf("User Home not passed")
 	}
 
-	oneRootPath, err := deps.GetOneAPIRoot()
-	if err != nil {
+	installs := deps.DiscoverRoots()
+	var oneRootPath string
+	if len(installs) == 0 {
 		log.Printf("Could not find oneAPI environment, will not check for missing dependencies")
+	} else {
+		oneRootPath = installs[0].Root
 	}
 	app := cview.NewApplication()
 
 	if app == nil {
 		return nil, fmt.Errorf("Failed to create backend application")
 	}
-	return &CLI{app: cview.NewApplication(), aggregator: a, userHome: uH, oneAPIRoot: oneRootPath}, nil
+	return &CLI{app: cview.NewApplication(), aggregator: a, userHome: uH, oneAPIRoot: oneRootPath, installs: installs}, nil
+}
+
+// SetOneAPIRoot sets the oneAPI installation dependencies are checked against
+func (cli *CLI) SetOneAPIRoot(root string) {
+	cli.oneAPIRoot = root
+}
+
+// SetVersion sets the CLI version recorded in created projects
+func (cli *CLI) SetVersion(version string) {
+	cli.version = version
 }
 
 // Show displays the UI
@@ -78,6 +99,11 @@ func (cli *CLI) Show() {
 		AddItem("Quit", "Press to exit", 'q', func() {
 			cli.app.Stop()
 		})
+	if len(cli.installs) > 1 {
+		list.InsertItem(2, "Select oneAPI installation", "", '3', func() {
+			cli.selectInstallation()
+		})
+	}
 
 	list.SetBorder(true)
 
@@ -102,6 +128,32 @@ func (cli *CLI) gotoLinkModel() {
 	cli.app.SetRoot(modal, true)
 }
 
+// selectInstallation chooses which of the oneAPI installations found
+// dependencies are checked against
+func (cli *CLI) selectInstallation() {
+	list := cview.NewList()
+
+	start := '1'
+	for _, in := range cli.installs {
+		root := in.Root
+		label := root
+		if root == cli.oneAPIRoot {
+			label += " (selected)"
+		}
+		list.AddItem(label, "found by "+in.Source, start, func() {
+			cli.oneAPIRoot = root
+			cli.app.SetRoot(cli.home, true)
+		})
+		start++
+	}
+	list.AddItem("Back", "", 'b', func() {
+		cli.app.SetRoot(cli.home, true)
+	})
+	list.SetBorder(true).SetTitle("Select oneAPI installation to check dependencies against")
+
+	cli.app.SetRoot(list, true)
+}
+
 func (cli *CLI) goBackPrj() {
 	if cli.langSelect != nil {
 		cli.app.SetRoot(cli.langSelect, true)
@@ -155,7 +207,7 @@ func (cli *CLI) selectProject(language string) {
 
 	inst := cview.NewTextView()
 	inst.SetBorder(true)
-	inst.SetText("Press Backspace to return to previous screen!")
+	inst.SetText("Press Backspace to return to previous screen! Press s to save the script installing missing dependencies")
 
 	flex := cview.NewFlex().
 		AddItem(cli.tree(language), 0, 1, true).
@@ -167,7 +219,11 @@ func (cli *CLI) selectProject(language string) {
 }
 
 func newSampleNode(s aggregator.Sample) *cview.TreeNode {
-	node := cview.NewTreeNode(s.Fields.Name).SetSelectable(true)
+	name := s.Fields.Name
+	if s.Local {
+		name += " (local)"
+	}
+	node := cview.NewTreeNode(name).SetSelectable(true)
 	node.SetReference(s)
 	return node
 }
@@ -248,7 +304,12 @@ func (cli *CLI) tree(language string) cview.Primitive {
 		return true
 	})
 
+	//script installs the missing dependencies of the sample shown, "" when none are
+	var script string
+	var scriptFor aggregator.Sample
+
 	tree.SetChangedFunc(func(node *cview.TreeNode) {
+		script = ""
 		reference := node.GetReference()
 		a, ok := reference.(aggregator.Sample)
 		if !ok {
@@ -261,12 +322,21 @@ func (cli *CLI) tree(language string) cview.Primitive {
 			if cli.oneAPIRoot == "" {
 				sideTextExtra = fmt.Sprintf(depsMissingEnvFmt, a.Fields.Dependencies)
 			} else {
-				sideTextExtra, _ = deps.CheckDeps(a.Fields.Dependencies, cli.oneAPIRoot)
+				report := deps.Check(a.Fields.Dependencies, cli.oneAPIRoot)
+				sideTextExtra = report.Message()
+				if plan := report.Plan(); !plan.Empty() {
+					script = plan.Script(runtime.GOOS)
+					scriptFor = a
+					sideTextExtra += "\nTo install what is missing run this script, press s to save it:\n\n" + script
+				}
 			}
 		}
 		sideTextExtra = cview.Escape(sideTextExtra)
 
 		newText := fmt.Sprintf("%s\n\n[red]%s", a.Fields.Description, sideTextExtra)
+		if a.Local {
+			newText = "[yellow]Sideloaded into the local cache, not from the sample aggregator[white]\n\n" + newText
+		}
 		cli.sidebar.SetText(newText)
 
 	}).SetSelectedFunc(func(node *cview.TreeNode) {
@@ -286,6 +356,10 @@ func (cli *CLI) tree(language string) cview.Primitive {
 			event.Key() == tcell.KeyBackspace {
 			cli.goBackPrj()
 		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' && script != "" {
+			cli.saveScript(scriptFor, script)
+			return nil
+		}
 
 		return event
 
@@ -294,6 +368,50 @@ func (cli *CLI) tree(language string) cview.Primitive {
 	return tree
 }
 
+// saveScript saves the script installing the missing dependencies of a sample
+// to the working directory, showing where in the sidebar. Existing files are
+// never replaced, the script gets a new name instead.
+func (cli *CLI) saveScript(sample aggregator.Sample, script string) {
+	ext := ".sh"
+	if runtime.GOOS == "windows" {
+		ext = ".bat"
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		pwd = "."
+	}
+	path, err := writeNewFile(pwd, "install-"+filepath.Base(sample.Path), ext, []byte(script))
+	msg := fmt.Sprintf("[green]The script was saved to %s[white]\n\n", cview.Escape(path))
+	if err != nil {
+		msg = fmt.Sprintf("[red]Failed to save the script - %s[white]\n\n", cview.Escape(err.Error()))
+	}
+	cli.sidebar.SetText(msg + cli.sidebar.GetText(false))
+}
+
+// writeNewFile writes data to <name><ext> in dir, or <name>-2<ext> and so on
+// when that exists, and returns the path written
+func writeNewFile(dir string, name string, ext string, data []byte) (string, error) {
+	for i := 1; i < 100; i++ {
+		path := filepath.Join(dir, name+ext)
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, ext))
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+	return "", fmt.Errorf("too many %s%s files in %s", name, ext, dir)
+}
+
 func isPathEmpty(path string) bool {
 	if !aggregator.FileExists(path) {
 		return true
@@ -314,66 +432,218 @@ func (cli *CLI) askPath(sample aggregator.Sample, language string, path string)
 		path = filepath.Join(pwd, filepath.Base(sample.Path))
 	}
 
+	//Values are kept while going back and forth on the same sample
+	vars := sample.Fields.TemplateVariables()
+	if cli.valuesFor != sample.Path {
+		cli.values = make(map[string]string)
+		for _, v := range vars {
+			cli.values[v.Name] = v.Default
+		}
+		cli.valuesFor = sample.Path
+	}
+
+	preview := cview.NewTextView()
+	preview.SetBorder(true).SetTitle("Preview")
+	showPreview := cli.previewer(preview, sample, language)
+	showPreview(path)
+
 	form := cview.NewForm().
 		AddInputField("Destination", path, 55, nil, func(t string) {
 			path = t
-		}).
-		AddButton("Create", func() {
-			path, err := cli.calcPath(path)
-			if err != nil {
-				return
-			}
-			if !isPathEmpty(path) {
-				cli.confirmOverwrite(sample, language, path)
-				return
-			}
-			outPath, err := cli.createProject(sample, language, path)
-
-			if err != nil {
-				cli.app.Stop()
-				log.Fatal(err)
-			}
-			cli.successModal(outPath)
+			showPreview(path)
+		})
+	for _, v := range vars {
+		name := v.Name
+		label := v.Description
+		if label == "" {
+			label = name
+		}
+		form.AddInputField(label, cli.values[name], 30, nil, func(t string) {
+			cli.values[name] = t
+			showPreview(path)
+		})
+	}
+	form.AddCheckbox("Initialise git repository", "", cli.gitInit, func(checked bool) {
+		cli.gitInit = checked
+	})
+	form.AddButton("Create", func() {
+		path, err := cli.calcPath(path)
+		if err != nil {
+			return
+		}
+		if _, err := cli.substitutions(sample); err != nil {
+			return // shown in the preview
+		}
+		if !isPathEmpty(path) {
+			cli.confirmOverwrite(sample, language, path)
+			return
+		}
+		cli.create(sample, language, path, extractor.Options{})
 
-		}).AddButton("Back", func() {
+	}).AddButton("Back", func() {
 		cli.selectProject(language)
 	})
 
 	form.SetBorder(true).SetTitle("Create Project").SetTitleAlign(cview.AlignLeft)
 	form.SetWrapAround(true)
 
-	cli.app.SetRoot(form, true)
+	flex := cview.NewFlex().SetDirection(cview.FlexRow).
+		AddItem(form, 9+2*len(vars), 0, true).
+		AddItem(preview, 0, 1, false)
+
+	cli.app.SetRoot(flex, true)
+}
+
+// previewer returns a function showing the sample contents, as named with
+// the current template values, against a destination in view
+func (cli *CLI) previewer(view *cview.TextView, sample aggregator.Sample, language string) func(path string) {
+	tarPath, tarErr := cli.tarBall(sample, language)
+	//The tarball is only listed again when the substitutions change, typing
+	//the destination only checks the listing against it
+	var listing *extractor.Listing
+	var listed []extractor.Substitution
+	return func(path string) {
+		err := tarErr
+		if err == nil {
+			var subs []extractor.Substitution
+			subs, err = cli.substitutions(sample)
+			if err == nil && (listing == nil || !reflect.DeepEqual(subs, listed)) {
+				listing, err = extractor.List(tarPath, extractor.Options{Substitutions: subs})
+				listed = subs
+			}
+		}
+		if err != nil {
+			listing = nil
+			view.SetText(fmt.Sprintf("Unable to preview the sample - %v", err))
+			return
+		}
+		dest, pathErr := cli.calcPath(path)
+		if pathErr != nil {
+			view.SetText(listing.String())
+			return
+		}
+		view.SetText(listing.Against(dest).String())
+	}
 }
 
 func (cli *CLI) confirmOverwrite(sample aggregator.Sample, language string, path string) {
+	tarPath, err := cli.tarBall(sample, language)
+	if err != nil {
+		cli.app.Stop()
+		log.Fatal(err)
+	}
+	subs, err := cli.substitutions(sample)
+	if err != nil {
+		cli.app.Stop()
+		log.Fatal(err)
+	}
+	conflicts, err := extractor.Conflicts(tarPath, path, extractor.Options{Substitutions: subs})
+	if err != nil {
+		cli.app.Stop()
+		log.Fatal(err)
+	}
+	if len(conflicts) == 0 {
+		cli.create(sample, language, path, extractor.Options{})
+		return
+	}
 
-	text := fmt.Sprintf("Path %s is not empty, Creating the sample may overwrite some files.", path)
-	buttons := []string{"Back", "Confirm"}
+	files := cview.NewTextView()
+	files.SetText(strings.Join(conflicts, "\n"))
+	files.SetBorder(true).SetTitle(fmt.Sprintf("%d file(s) already exist in %s", len(conflicts), path))
 
-	modal := cview.NewModal().
-		SetText(text).
-		AddButtons(buttons).
-		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+	form := cview.NewForm().
+		AddButton("Back", func() {
+			cli.askPath(sample, language, path)
+		}).
+		AddButton("Overwrite", func() {
+			cli.create(sample, language, path, extractor.Options{OnConflict: extractor.ConflictOverwrite})
+		}).
+		AddButton("Skip existing", func() {
+			cli.create(sample, language, path, extractor.Options{OnConflict: extractor.ConflictSkip})
+		}).
+		AddButton("Backup to "+extractor.BackupSuffix, func() {
+			cli.create(sample, language, path, extractor.Options{OnConflict: extractor.ConflictBackup})
+		}).
+		AddButton("Choose per file", func() {
+			cli.resolvePerFile(sample, language, path, conflicts, make(map[string]extractor.ConflictPolicy))
+		})
+	form.SetWrapAround(true)
 
-			if buttonLabel != "Back" {
-				outPath, err := cli.createProject(sample, language, path)
+	flex := cview.NewFlex().SetDirection(cview.FlexRow).
+		AddItem(files, 0, 1, false).
+		AddItem(form, 3, 0, true)
 
-				if err != nil {
-					cli.app.Stop()
-					log.Fatal(err)
+	cli.app.SetRoot(flex, true)
+}
+
+// resolvePerFile asks what to do with each colliding file in turn, then creates the project
+func (cli *CLI) resolvePerFile(sample aggregator.Sample, language string, path string, conflicts []string, decisions map[string]extractor.ConflictPolicy) {
+	if len(decisions) == len(conflicts) {
+		cli.create(sample, language, path, extractor.Options{
+			OnConflict: extractor.ConflictAsk,
+			Ask: func(name string) (extractor.ConflictPolicy, error) {
+				p, ok := decisions[name]
+				if !ok {
+					return extractor.ConflictFail, fmt.Errorf("no decision made for %s", name)
 				}
-				cli.successModal(outPath)
+				return p, nil
+			},
+		})
+		return
+	}
+
+	name := conflicts[len(decisions)]
+	text := fmt.Sprintf("(%d/%d) %s already exists in %s", len(decisions)+1, len(conflicts), name, path)
+	choices := map[string]extractor.ConflictPolicy{
+		"Overwrite": extractor.ConflictOverwrite,
+		"Skip":      extractor.ConflictSkip,
+		"Backup":    extractor.ConflictBackup,
+	}
+
+	modal := cview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Overwrite", "Skip", "Backup", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			p, ok := choices[buttonLabel]
+			if !ok {
+				cli.confirmOverwrite(sample, language, path)
 				return
 			}
-			cli.askPath(sample, language, path)
+			decisions[name] = p
+			cli.resolvePerFile(sample, language, path, conflicts, decisions)
 		})
 	cli.app.SetRoot(modal, true)
+}
 
+// create creates the project and shows the outcome, failures are fatal
+func (cli *CLI) create(sample aggregator.Sample, language string, path string, opts extractor.Options) {
+	outPath, err := cli.createProject(sample, language, path, opts)
+	if err != nil {
+		cli.app.Stop()
+		log.Fatal(err)
+	}
+
+	var note string
+	if cli.gitInit {
+		created, err := project.InitGit(outPath)
+		switch {
+		case err != nil:
+			note = fmt.Sprintf("Failed to initialise a git repository - %v", err)
+		case !created:
+			note = "Already inside a git repository, no new repository was initialised."
+		default:
+			note = "Initialised a git repository with the sample as first commit."
+		}
+	}
+	cli.successModal(outPath, note)
 }
 
-func (cli *CLI) successModal(path string) {
+func (cli *CLI) successModal(path string, note string) {
 
 	text := fmt.Sprintf("Sucessfully created project in %s", path)
+	if note != "" {
+		text = fmt.Sprintf("%s\n\n%s", text, note)
+	}
 	buttons := []string{"Quit"}
 	printReadmeText := "View Readme and Quit"
 
@@ -430,17 +700,44 @@ func (cli *CLI) calcPath(projectPath string) (string, error) {
 	return projectPath, nil
 }
 
-func (cli *CLI) createProject(selectedSample aggregator.Sample, lang string, projectPath string) (output string, err error) {
+func (cli *CLI) tarBall(selectedSample aggregator.Sample, lang string) (string, error) {
 	//Maybe here we might check if the tarball does not exists and the trigger the aggregator to atempt an update
+	return cli.aggregator.SampleTarBall(lang, selectedSample)
+}
+
+// substitutions returns the substitutions for the template values entered for sample
+func (cli *CLI) substitutions(sample aggregator.Sample) ([]extractor.Substitution, error) {
+	if cli.valuesFor != sample.Path {
+		return project.Substitutions(sample.Fields.TemplateVariables(), nil)
+	}
+	return project.Substitutions(sample.Fields.TemplateVariables(), cli.values)
+}
 
-	tarPath, err := aggregator.GetTarBall(cli.aggregator.GetLocalPath(), cli.aggregator.GetURL(), lang, selectedSample.Path)
+func (cli *CLI) createProject(selectedSample aggregator.Sample, lang string, projectPath string, opts extractor.Options) (output string, err error) {
+	tarPath, err := cli.tarBall(selectedSample, lang)
+	if err != nil {
+		return "", err
+	}
+	opts.Substitutions, err = cli.substitutions(selectedSample)
 	if err != nil {
 		return "", err
 	}
 
-	err = extractor.ExtractTarGz(tarPath, projectPath)
+	src := project.Source{
+		Name:     selectedSample.Fields.Name,
+		URL:      cli.aggregator.GetURL(),
+		Language: lang,
+		Path:     selectedSample.Path,
+		SHA:      selectedSample.SHA,
+	}
+	src.IndexVersion, _ = cli.aggregator.IndexVersion(lang)
+
+	_, err = project.Create(tarPath, projectPath, src, opts, cli.version)
 	if err != nil {
 		return "", err
 	}
+	if err := cli.aggregator.Pin(aggregator.Pin{Language: src.Language, Path: src.Path, SHA: src.SHA}); err != nil {
+		log.Println(err)
+	}
 	return projectPath, nil
 }
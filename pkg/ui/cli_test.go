@@ -14,6 +14,7 @@ import (
 
 	"github.com/gdamore/tcell"
 	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
 )
 
 func mkTestScreen(t *testing.T, charset string) tcell.SimulationScreen {
@@ -154,6 +155,7 @@ func TestFullFlow(t *testing.T) {
 		time.Sleep(10 * time.Millisecond) //Need to give time to the key presses :/
 	}
 
+	s.InjectKey(tcell.KeyTAB, 'd', tcell.ModNone)   //Tab to git checkbox
 	s.InjectKey(tcell.KeyTAB, 'd', tcell.ModNone)   //Tab
 	s.InjectKey(tcell.KeyEnter, 'd', tcell.ModNone) //Create
 	s.InjectKey(tcell.KeyEnter, 'd', tcell.ModNone) //Dismiss success dialog
@@ -168,6 +170,14 @@ func TestFullFlow(t *testing.T) {
 		t.Errorf("sample creation flow failed! could not find %s", knownZebra)
 	}
 
+	m, err := project.ReadManifest(ws)
+	if err != nil {
+		t.Fatalf("sample creation flow did not record the sample - %v", err)
+	}
+	if m.Sample.Path != "zoo" || m.Sample.SHA != "1" || m.Sample.Language != "cpp" {
+		t.Errorf("recorded the wrong sample %v", m.Sample)
+	}
+
 }
 
 func fileExists(t *testing.T, path string) bool {
@@ -177,3 +187,90 @@ func fileExists(t *testing.T, path string) bool {
 	}
 	return true
 }
+
+func TestConflictSkipFlow(t *testing.T) {
+	td := setupAggregatorTest(t)
+	defer td.cleanup()
+	cli, err := NewCLI(td.aggregator, td.home)
+	if err != nil {
+		t.Error(err)
+	}
+	s := mkTestScreen(t, "")
+
+	cli.app = cli.app.SetScreen(s)
+
+	ws, err := ioutil.TempDir("", "ws")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(ws)
+
+	knownZebra := filepath.Join(ws, "this-is-a-zebra.md")
+	const mine = "my own zebra, do not touch"
+	if err := ioutil.WriteFile(knownZebra, []byte(mine), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cli.Show() //Let the CLI run in another routine
+	}()
+
+	s.InjectKey(tcell.KeyRune, '1', tcell.ModNone)  //Main Screen
+	s.InjectKey(tcell.KeyRune, '1', tcell.ModNone)  //Langauge Select
+	s.InjectKey(tcell.KeyDown, 'd', tcell.ModNone)  //Get the zebra
+	s.InjectKey(tcell.KeyEnter, 'd', tcell.ModNone) //Enter the sample
+
+	s.InjectKey(tcell.KeyCtrlU, 'd', tcell.ModNone)
+	for i := 0; i < len(ws); i++ {
+		s.InjectKey(tcell.KeyRune, rune(ws[i]), tcell.ModNone)
+		time.Sleep(10 * time.Millisecond) //Need to give time to the key presses :/
+	}
+
+	s.InjectKey(tcell.KeyTAB, 'd', tcell.ModNone)   //Tab to git checkbox
+	s.InjectKey(tcell.KeyTAB, 'd', tcell.ModNone)   //Tab
+	s.InjectKey(tcell.KeyEnter, 'd', tcell.ModNone) //Create, the zebra collides
+	time.Sleep(50 * time.Millisecond)
+	s.InjectKey(tcell.KeyTAB, 'd', tcell.ModNone)   //Back -> Overwrite
+	s.InjectKey(tcell.KeyTAB, 'd', tcell.ModNone)   //Overwrite -> Skip existing
+	s.InjectKey(tcell.KeyEnter, 'd', tcell.ModNone) //Skip existing
+	s.InjectKey(tcell.KeyEnter, 'd', tcell.ModNone) //Dismiss success dialog
+
+	wg.Wait()
+
+	b, err := ioutil.ReadFile(knownZebra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != mine {
+		t.Errorf("skip existing replaced %s", knownZebra)
+	}
+}
+
+func TestWriteNewFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "install-zoo.sh")
+	if err := ioutil.WriteFile(existing, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := writeNewFile(dir, "install-zoo", ".sh", []byte("script"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "install-zoo-2.sh") {
+		t.Errorf("expected a new name, got %s", path)
+	}
+	if b, _ := ioutil.ReadFile(existing); string(b) != "mine" {
+		t.Errorf("an existing file must not be replaced, got %q", b)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "script" {
+		t.Errorf("expected the script to be written, got %q", b)
+	}
+}
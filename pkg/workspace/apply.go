@@ -0,0 +1,163 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/extractor"
+	"github.com/intel/oneapi-cli/pkg/project"
+)
+
+// Outcomes of applying a project
+const (
+	StatusCreated  = "created"
+	StatusUpToDate = "up-to-date" // already created from the same version, untouched
+	StatusOutdated = "outdated"   // created from another version, untouched
+	StatusFailed   = "failed"
+)
+
+// DefaultJobs is the number of projects created at once
+const DefaultJobs = 4
+
+// Fetcher resolves the sample of a project, honouring a pinned SHA, and
+// returns its tarball, where it came from and its template variables
+type Fetcher func(p Project) (tarPath string, src project.Source, vars []aggregator.TemplateVariable, err error)
+
+// Resolver names the sample of a project as the index does, by its path, so
+// projects naming it by path or by name are known to use the same sample
+type Resolver func(language string, sample string) string
+
+// Options controls how a workspace is applied
+type Options struct {
+	Jobs       int
+	CLIVersion string
+	Resolve    Resolver // nil when projects name their samples by path
+	Exact      bool     // projects created from another version fail rather than being outdated
+}
+
+// Result is the outcome for a single project
+type Result struct {
+	Language    string `json:"language"`
+	Sample      string `json:"sample"`
+	Destination string `json:"destination"`
+	SHA         string `json:"sha,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Apply creates every project of the workspace that does not exist yet,
+// in parallel. It is safe to run again: projects already created from the
+// same sample are left alone. Results are in the order of the projects.
+func (w *Workspace) Apply(fetch Fetcher, opts Options) []Result {
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = DefaultJobs
+	}
+
+	results := make([]Result, len(w.Projects))
+	fetch = serialise(fetch, opts.Resolve)
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = w.apply(w.Projects[i], fetch, opts)
+			}
+		}()
+	}
+	for i := range w.Projects {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
+
+func (w *Workspace) apply(p Project, fetch Fetcher, opts Options) Result {
+	r := Result{Language: p.Language, Sample: p.Sample, Destination: w.Destination(p), SHA: p.SHA}
+	fail := func(err error) Result {
+		r.Status = StatusFailed
+		r.Error = err.Error()
+		return r
+	}
+
+	existing, err := project.ReadManifest(r.Destination)
+	if err != nil && !os.IsNotExist(err) {
+		return fail(err)
+	}
+	//A pinned project that is already there needs no lookup at all
+	if existing != nil && p.SHA != "" && existing.Sample.SHA == p.SHA && sameSample(existing.Sample, p) {
+		r.Status = StatusUpToDate
+		return r
+	}
+
+	tarPath, src, vars, err := fetch(p)
+	if err != nil {
+		return fail(err)
+	}
+	r.SHA = src.SHA
+
+	if existing != nil {
+		if existing.Sample.Language != src.Language || existing.Sample.Path != src.Path {
+			return fail(fmt.Errorf("%s already holds the %s sample %s", r.Destination, existing.Sample.Language, existing.Sample.Path))
+		}
+		r.Status = StatusUpToDate
+		if existing.Sample.SHA != src.SHA && opts.Exact {
+			r.SHA = existing.Sample.SHA
+			return fail(fmt.Errorf("%s holds %s, not %s", r.Destination, existing.Sample.SHA, src.SHA))
+		}
+		if existing.Sample.SHA != src.SHA {
+			r.Status = StatusOutdated
+			r.SHA = existing.Sample.SHA
+		}
+		return r
+	}
+
+	subs, err := project.Substitutions(vars, p.Set)
+	if err != nil {
+		return fail(err)
+	}
+	//Never touch files that were not created by us
+	xo := extractor.Options{OnConflict: extractor.ConflictFail, Substitutions: subs}
+	if _, err := project.Create(tarPath, r.Destination, src, xo, opts.CLIVersion); err != nil {
+		return fail(err)
+	}
+	r.Status = StatusCreated
+	return r
+}
+
+func sameSample(s project.Source, p Project) bool {
+	return s.Language == p.Language && (s.Path == p.Sample || s.Name == p.Sample)
+}
+
+// serialise makes sure the same sample is never fetched twice at once, so
+// concurrent projects using it do not download into the same file
+func serialise(fetch Fetcher, resolve Resolver) Fetcher {
+	var mu sync.Mutex
+	locks := make(map[string]*sync.Mutex)
+	return func(p Project) (string, project.Source, []aggregator.TemplateVariable, error) {
+		sample := p.Sample
+		if resolve != nil {
+			sample = resolve(p.Language, p.Sample)
+		}
+		key := p.Language + "/" + sample
+		mu.Lock()
+		l, ok := locks[key]
+		if !ok {
+			l = &sync.Mutex{}
+			locks[key] = l
+		}
+		mu.Unlock()
+
+		l.Lock()
+		defer l.Unlock()
+		return fetch(p)
+	}
+}
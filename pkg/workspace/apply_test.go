@@ -0,0 +1,159 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package workspace
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
+)
+
+// testIndex serves samples from testdata, at whichever SHA is current
+// unless a project is pinned, as if every version were cached
+type testIndex struct {
+	mu      sync.Mutex
+	sha     string
+	fetched int
+}
+
+func (ti *testIndex) fetch(p Project) (string, project.Source, []aggregator.TemplateVariable, error) {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	ti.fetched++
+	if p.Sample == "missing" {
+		return "", project.Source{}, nil, fmt.Errorf("no sample %s", p.Sample)
+	}
+	sha := ti.sha
+	if p.SHA != "" {
+		sha = p.SHA
+	}
+	src := project.Source{Language: p.Language, Path: p.Sample, SHA: sha, URL: "http://test"}
+	return filepath.Join("testdata", sha+".tar.gz"), src, nil, nil
+}
+
+func TestApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	//Already holds an unrelated file that the sample would replace
+	if err := os.MkdirAll(filepath.Join(dir, "taken"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "taken", "keep.txt"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Workspace{dir: dir, Projects: []Project{
+		{Language: "cpp", Sample: "a", Destination: "a"},
+		{Language: "cpp", Sample: "a", Destination: "b"},
+		{Language: "cpp", Sample: "b", Destination: "pinned", SHA: "v1"},
+		{Language: "cpp", Sample: "missing", Destination: "missing"},
+		{Language: "cpp", Sample: "a", Destination: "taken"},
+	}}
+	index := &testIndex{sha: "v1"}
+
+	statuses := func(results []Result) []string {
+		var s []string
+		for _, r := range results {
+			s = append(s, r.Status)
+		}
+		return s
+	}
+	check := func(results []Result, expected ...string) {
+		t.Helper()
+		got := statuses(results)
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("project %d: expected %s, got %s (%s)", i, expected[i], got[i], results[i].Error)
+			}
+		}
+	}
+
+	results := w.Apply(index.fetch, Options{Jobs: 3})
+	check(results, StatusCreated, StatusCreated, StatusCreated, StatusFailed, StatusFailed)
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "taken", "keep.txt")); string(b) != "mine" {
+		t.Errorf("existing files must not be replaced, got %q", b)
+	}
+	if _, err := project.ReadManifest(filepath.Join(dir, "a")); err != nil {
+		t.Errorf("created project has no manifest - %v", err)
+	}
+
+	//Running again changes nothing, pinned projects are not even looked up
+	index.fetched = 0
+	results = w.Apply(index.fetch, Options{})
+	check(results, StatusUpToDate, StatusUpToDate, StatusUpToDate, StatusFailed, StatusFailed)
+	if index.fetched != 4 {
+		t.Errorf("expected only the unpinned projects to be fetched, got %d", index.fetched)
+	}
+
+	//A newer sample is reported, not applied
+	index.sha = "v2"
+	results = w.Apply(index.fetch, Options{})
+	check(results, StatusOutdated, StatusOutdated, StatusUpToDate)
+	if results[0].SHA != "v1" {
+		t.Errorf("outdated projects should report the version they hold, got %s", results[0].SHA)
+	}
+
+	//Unless the exact version is required
+	results = w.Apply(index.fetch, Options{Exact: true})
+	check(results, StatusFailed, StatusFailed, StatusUpToDate)
+	if results[0].SHA != "v1" || results[0].Error == "" {
+		t.Errorf("expected the held version and an error, got %+v", results[0])
+	}
+
+	//A destination holding a different sample is an error
+	w.Projects[0].Sample = "b"
+	if r := w.Apply(index.fetch, Options{})[0]; r.Status != StatusFailed {
+		t.Errorf("expected a different sample in the destination to fail, got %s", r.Status)
+	}
+}
+
+func TestSerialiseResolvesSamples(t *testing.T) {
+	var mu sync.Mutex
+	busy := make(map[string]bool)
+	overlapped := false
+	fetch := serialise(func(p Project) (string, project.Source, []aggregator.TemplateVariable, error) {
+		mu.Lock()
+		if busy[p.Language] {
+			overlapped = true
+		}
+		busy[p.Language] = true
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		busy[p.Language] = false
+		mu.Unlock()
+		return "", project.Source{}, nil, nil
+	}, func(language string, sample string) string {
+		if sample == "Matrix Mul" {
+			return "matrix_mul"
+		}
+		return sample
+	})
+
+	var wg sync.WaitGroup
+	for _, sample := range []string{"matrix_mul", "Matrix Mul", "matrix_mul", "Matrix Mul"} {
+		wg.Add(1)
+		go func(sample string) {
+			defer wg.Done()
+			fetch(Project{Language: "cpp", Sample: sample})
+		}(sample)
+	}
+	wg.Wait()
+	if overlapped {
+		t.Errorf("a sample named by path and by name was fetched twice at once")
+	}
+}
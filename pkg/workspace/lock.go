@@ -0,0 +1,166 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
+)
+
+// LockVersion is the version of the lockfile format written by this CLI
+const LockVersion = 1
+
+// LockedProject pins a project of a workspace to the exact bytes of its sample
+type LockedProject struct {
+	Language    string `json:"language"`
+	Sample      string `json:"sample"` // always the path in the index
+	Name        string `json:"name,omitempty"`
+	Destination string `json:"destination"`
+	SHA         string `json:"sha"`
+	URL         string `json:"url"`
+	// Digest is the sha512 of the sample tarball
+	Digest    string                        `json:"digest"`
+	Set       map[string]string             `json:"set,omitempty"`
+	Variables []aggregator.TemplateVariable `json:"variables,omitempty"`
+}
+
+// Lockfile pins every project of a workspace
+type Lockfile struct {
+	Version  int             `json:"version"`
+	Projects []LockedProject `json:"projects"`
+
+	dir string
+}
+
+// LockPath is the lockfile kept next to a workspace file, i.e.
+// workspace.yaml is locked in workspace.lock
+func LockPath(workspacePath string) string {
+	return strings.TrimSuffix(workspacePath, filepath.Ext(workspacePath)) + ".lock"
+}
+
+// Lock pins every project of the workspace. Projects that were already
+// created are pinned to the version they were created from, the others to
+// the version fetch returns.
+func (w *Workspace) Lock(fetch Fetcher) (*Lockfile, error) {
+	l := &Lockfile{Version: LockVersion, dir: w.dir}
+	for _, p := range w.Projects {
+		m, err := project.ReadManifest(w.Destination(p))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s - %v", p.Destination, err)
+		}
+		if m != nil && sameSample(m.Sample, p) {
+			p.SHA = m.Sample.SHA
+		}
+
+		tarPath, src, vars, err := fetch(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s sample %s - %v", p.Language, p.Sample, err)
+		}
+		if src.SHA == "" {
+			return nil, fmt.Errorf("failed to lock %s sample %s - the index does not give its SHA", p.Language, p.Sample)
+		}
+		digest, err := aggregator.FileDigest(tarPath)
+		if err != nil {
+			return nil, err
+		}
+		l.Projects = append(l.Projects, LockedProject{
+			Language:    src.Language,
+			Sample:      src.Path,
+			Name:        src.Name,
+			Destination: p.Destination,
+			SHA:         src.SHA,
+			URL:         src.URL,
+			Digest:      digest,
+			Set:         p.Set,
+			Variables:   vars,
+		})
+	}
+	return l, nil
+}
+
+// ReadLock reads a lockfile, relative destinations are relative to it
+func ReadLock(path string) (*Lockfile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l Lockfile
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s - %v", path, err)
+	}
+	if l.Version > LockVersion {
+		return nil, fmt.Errorf("%s was written by a newer CLI (version %d), please update", path, l.Version)
+	}
+	l.dir, err = filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := l.Workspace().validate(); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s - %v", path, err)
+	}
+	for _, p := range l.Projects {
+		if p.SHA == "" || p.Digest == "" {
+			return nil, fmt.Errorf("invalid lockfile %s - %s is not pinned", path, p.Destination)
+		}
+	}
+	return &l, nil
+}
+
+// Write stores the lockfile
+func (l *Lockfile) Write(path string) error {
+	b, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Workspace is the workspace the lockfile pins, every project has its SHA set
+func (l *Lockfile) Workspace() *Workspace {
+	w := &Workspace{dir: l.dir}
+	for _, p := range l.Projects {
+		w.Projects = append(w.Projects, Project{
+			Language:    p.Language,
+			Sample:      p.Sample,
+			Destination: p.Destination,
+			SHA:         p.SHA,
+			Set:         p.Set,
+		})
+	}
+	return w
+}
+
+// Find returns the locked project matching a project of Workspace
+func (l *Lockfile) Find(p Project) (LockedProject, bool) {
+	for _, lp := range l.Projects {
+		if lp.Destination == p.Destination {
+			return lp, true
+		}
+	}
+	return LockedProject{}, false
+}
+
+// Verify checks a tarball is the one the project was locked to
+func (lp LockedProject) Verify(tarPath string) error {
+	digest, err := aggregator.FileDigest(tarPath)
+	if err != nil {
+		return err
+	}
+	if digest != lp.Digest {
+		return fmt.Errorf("%s does not match the locked digest of %s sample %s at %s", tarPath, lp.Language, lp.Sample, lp.SHA)
+	}
+	return nil
+}
+
+// Source is where the locked project came from
+func (lp LockedProject) Source() project.Source {
+	return project.Source{Name: lp.Name, URL: lp.URL, Language: lp.Language, Path: lp.Sample, SHA: lp.SHA}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/intel/oneapi-cli/pkg/aggregator"
+	"github.com/intel/oneapi-cli/pkg/project"
+)
+
+func TestLockPath(t *testing.T) {
+	for path, expected := range map[string]string{"ws.yaml": "ws.lock", "a/ws.json": "a/ws.lock", "ws": "ws.lock"} {
+		if l := LockPath(path); l != expected {
+			t.Errorf("LockPath(%s) = %s, expected %s", path, l, expected)
+		}
+	}
+}
+
+func TestLockRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	created := Project{Language: "cpp", Sample: "a", Destination: "created"}
+	index := &testIndex{sha: "v1"}
+	(&Workspace{dir: dir, Projects: []Project{created}}).Apply(index.fetch, Options{})
+
+	w := &Workspace{dir: dir, Projects: []Project{created, {Language: "cpp", Sample: "a", Destination: "new"}}}
+
+	//Created projects stay pinned to what they were created from
+	index.sha = "v2"
+	l, err := w.Lock(index.fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Projects[0].SHA != "v1" || l.Projects[1].SHA != "v2" {
+		t.Errorf("expected created project locked to v1 and new one to v2, got %s and %s", l.Projects[0].SHA, l.Projects[1].SHA)
+	}
+	v1Digest, _ := aggregator.FileDigest(filepath.Join("testdata", "v1.tar.gz"))
+	if l.Projects[0].Digest != v1Digest {
+		t.Errorf("locked digest does not match the tarball")
+	}
+
+	lockPath := filepath.Join(dir, "ws.lock")
+	if err := l.Write(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	l, err = ReadLock(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Restore somewhere else from the lock alone
+	if err := os.RemoveAll(filepath.Join(dir, "created")); err != nil {
+		t.Fatal(err)
+	}
+	restored := l.Workspace()
+	results := restored.Apply(func(p Project) (string, project.Source, []aggregator.TemplateVariable, error) {
+		lp, _ := l.Find(p)
+		tarPath := filepath.Join("testdata", lp.SHA+".tar.gz")
+		return tarPath, lp.Source(), lp.Variables, lp.Verify(tarPath)
+	}, Options{})
+	for _, r := range results {
+		if r.Status != StatusCreated {
+			t.Errorf("%s was not restored: %s %s", r.Destination, r.Status, r.Error)
+		}
+	}
+	m, err := project.ReadManifest(filepath.Join(dir, "created"))
+	if err != nil || m.Sample.SHA != "v1" {
+		t.Errorf("restored project should be at the locked version, got %v %v", m, err)
+	}
+
+	if err := l.Projects[0].Verify(filepath.Join("testdata", "v2.tar.gz")); err == nil {
+		t.Errorf("a tarball with other content should not verify")
+	}
+}
+
+func TestReadLockInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{
+		"newer.lock":    `{"version":99,"projects":[{"language":"cpp","sample":"a","destination":"a","sha":"1","digest":"d"}]}`,
+		"unpinned.lock": `{"version":1,"projects":[{"language":"cpp","sample":"a","destination":"a","digest":"d"}]}`,
+		"empty.lock":    `{"version":1,"projects":[]}`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadLock(path); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package workspace creates a set of samples described in a single file
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project is a sample to create as part of a workspace
+type Project struct {
+	Language string `json:"language" yaml:"language"`
+	// Sample is the path of the sample in the index, or its name
+	Sample string `json:"sample" yaml:"sample"`
+	// Destination is relative to the workspace file unless absolute
+	Destination string `json:"destination" yaml:"destination"`
+	// SHA pins the version of the sample, optional
+	SHA string `json:"sha,omitempty" yaml:"sha,omitempty"`
+	// Set gives values for the template variables of the sample
+	Set map[string]string `json:"set,omitempty" yaml:"set,omitempty"`
+}
+
+// Workspace is a list of samples to create together
+type Workspace struct {
+	Projects []Project `json:"projects" yaml:"projects"`
+
+	dir string
+}
+
+// Load reads a workspace file, .json files are read as JSON and anything
+// else as YAML
+func Load(path string) (*Workspace, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var w Workspace
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &w)
+	} else {
+		err = yaml.Unmarshal(b, &w)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace file %s - %v", path, err)
+	}
+
+	w.dir, err = filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := w.validate(); err != nil {
+		return nil, fmt.Errorf("invalid workspace file %s - %v", path, err)
+	}
+	return &w, nil
+}
+
+// Destination is where the project is created
+func (w *Workspace) Destination(p Project) string {
+	dest := filepath.FromSlash(p.Destination)
+	if filepath.IsAbs(dest) {
+		return filepath.Clean(dest)
+	}
+	return filepath.Join(w.dir, dest)
+}
+
+func (w *Workspace) validate() error {
+	if len(w.Projects) == 0 {
+		return fmt.Errorf("no projects listed")
+	}
+	seen := make(map[string]int)
+	for i, p := range w.Projects {
+		switch {
+		case p.Language == "":
+			return fmt.Errorf("project %d has no language", i+1)
+		case p.Sample == "":
+			return fmt.Errorf("project %d has no sample", i+1)
+		case p.Destination == "":
+			return fmt.Errorf("project %d has no destination", i+1)
+		}
+		dest := w.Destination(p)
+		if j, ok := seen[dest]; ok {
+			return fmt.Errorf("projects %d and %d are both created in %s", j+1, i+1, dest)
+		}
+		seen[dest] = i
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Intel Corporation
+// SPDX-License-Identifier: BSD-3-Clause
+
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	w, err := Load(filepath.Join("testdata", "workspace.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Project{
+		{Language: "cpp", Sample: "zoo", Destination: "projects/zoo"},
+		{Language: "python", Sample: "zoo-zebra", Destination: "/tmp/zebra", SHA: "1", Set: map[string]string{"PROJECT": "zebra"}},
+	}
+	if !reflect.DeepEqual(w.Projects, expected) {
+		t.Errorf("expected %v, got %v", expected, w.Projects)
+	}
+
+	abs, _ := filepath.Abs(filepath.Join("testdata", "projects", "zoo"))
+	if d := w.Destination(w.Projects[0]); d != abs {
+		t.Errorf("relative destinations should be relative to the workspace file, expected %s got %s", abs, d)
+	}
+	if d := w.Destination(w.Projects[1]); d != filepath.FromSlash("/tmp/zebra") {
+		t.Errorf("absolute destinations should be kept, got %s", d)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{
+		"json.json":      `{"projects":[{"language":"cpp","sample":"zoo","destination":"a"}]}`,
+		"empty.yaml":     `projects: []`,
+		"nolang.yaml":    `projects: [{sample: zoo, destination: a}]`,
+		"duplicate.yaml": `projects: [{language: cpp, sample: zoo, destination: a}, {language: cpp, sample: cat, destination: ./a}]`,
+		"broken.json":    `projects: []`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		if name == "json.json" {
+			if err != nil {
+				t.Errorf("%s should load - %v", name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+}